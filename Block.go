@@ -2,7 +2,9 @@ package ui
 
 import (
 	"encoding/json"
-	"reflect"
+	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/dracory/uid"
 )
@@ -18,19 +20,27 @@ func NewBlock() BlockInterface {
 	return block
 }
 
+// NewBlockFromJson creates a block from its JSON representation
+//
+// Returns a *DecodeError with the path of the offending field,
+// if the JSON is not a valid block
+//
+// No decode limits are enforced, to decode JSON from untrusted
+// sources use NewBlockFromJsonWithOptions
 func NewBlockFromJson(blockJson string) (BlockInterface, error) {
-	blockMap := map[string]any{}
-
-	err := json.Unmarshal([]byte(blockJson), &blockMap)
+	block, err := decodeBlockJson([]byte(blockJson), "$", 1, &decodeState{})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return ConvertMapToBlock(blockMap)
+	return block, nil
 }
 
-// BlockFromMap creates a block from a map
+// NewBlockFromMap creates a block from a map
+//
+// Fields of unexpected types are ignored. To validate the map,
+// and get an error for invalid fields, use ConvertMapToBlock
 func NewBlockFromMap(m map[string]any) BlockInterface {
 	id := ""
 
@@ -44,34 +54,43 @@ func NewBlockFromMap(m map[string]any) BlockInterface {
 		blockType = blockTypeMap
 	}
 
+	content := ""
+
+	if contentMap, ok := m["content"].(string); ok {
+		content = contentMap
+	}
+
 	parameters := map[string]string{}
 
-	if parametersMap, ok := m["parameters"].(map[string]string); ok {
+	switch parametersMap := m["parameters"].(type) {
+	case map[string]string:
 		for k, v := range parametersMap {
 			parameters[k] = v
 		}
+	case map[string]any:
+		for k, v := range parametersMap {
+			if value, ok := v.(string); ok {
+				parameters[k] = value
+			}
+		}
 	}
 
 	children := []BlockInterface{}
 
-	if childrenAny, ok := m["children"]; ok {
-		typeOfChildren := reflect.TypeOf(childrenAny).Elem()
-
-		kind := typeOfChildren.Kind()
-
-		if kind == reflect.Interface {
-			childrenMap := childrenAny.([]BlockInterface)
-			children = childrenMap
+	switch childrenAny := m["children"].(type) {
+	case []BlockInterface:
+		children = childrenAny
+	case []map[string]any:
+		for _, c := range childrenAny {
+			children = append(children, NewBlockFromMap(c))
 		}
-
-		if kind == reflect.Map {
-			childrenMap := childrenAny.([]map[string]any)
-			for _, c := range childrenMap {
-				child := NewBlockFromMap(c)
-				if child == nil {
-					continue
-				}
+	case []any:
+		for _, c := range childrenAny {
+			switch child := c.(type) {
+			case BlockInterface:
 				children = append(children, child)
+			case map[string]any:
+				children = append(children, NewBlockFromMap(child))
 			}
 		}
 	}
@@ -79,6 +98,7 @@ func NewBlockFromMap(m map[string]any) BlockInterface {
 	block := NewBlock()
 	block.SetID(id)
 	block.SetType(blockType)
+	block.SetContent(content)
 	block.SetParameters(parameters)
 	block.SetChildren(children)
 	return block
@@ -89,8 +109,10 @@ func NewBlockFromMap(m map[string]any) BlockInterface {
 type Block struct {
 	id         string
 	blockType  string
+	content    string
 	children   []BlockInterface
 	parameters map[string]string
+	observer   *TreeObserver
 }
 
 // type BlockConfig struct {
@@ -103,17 +125,54 @@ type Block struct {
 // == INTERFACE IMPLEMENTATION ================================================
 
 func (b *Block) AddChild(child BlockInterface) {
+	oldChildren := b.children
 	if b.children == nil {
 		b.children = []BlockInterface{}
 	}
 	b.children = append(b.children, child)
+	b.childrenChanged(oldChildren)
 }
 
 func (b *Block) AddChildren(children []BlockInterface) {
+	oldChildren := b.children
 	if b.children == nil {
 		b.children = []BlockInterface{}
 	}
 	b.children = append(b.children, children...)
+	b.childrenChanged(oldChildren)
+}
+
+// InsertChildAt inserts the child at the index, shifting the children
+// at and after the index. The index must be between 0 and the number
+// of children (to append), otherwise ErrIndexOutOfRange is returned
+func (b *Block) InsertChildAt(index int, child BlockInterface) error {
+	if index < 0 || index > len(b.children) {
+		return fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+	}
+
+	oldChildren := b.children
+	b.children = slices.Insert(slices.Clone(b.children), index, child)
+	b.childrenChanged(oldChildren)
+
+	return nil
+}
+
+// RemoveChild removes the direct child with the ID,
+// returns ErrBlockNotFound if there is no such child
+func (b *Block) RemoveChild(id string) error {
+	index := slices.IndexFunc(b.children, func(child BlockInterface) bool {
+		return child != nil && child.ID() == id
+	})
+
+	if index < 0 {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	oldChildren := b.children
+	b.children = slices.Delete(slices.Clone(b.children), index, index+1)
+	b.childrenChanged(oldChildren)
+
+	return nil
 }
 
 func (b *Block) Children() []BlockInterface {
@@ -121,7 +180,9 @@ func (b *Block) Children() []BlockInterface {
 }
 
 func (b *Block) SetChildren(children []BlockInterface) {
+	oldChildren := b.children
 	b.children = children
+	b.childrenChanged(oldChildren)
 }
 
 func (b *Block) ID() string {
@@ -129,7 +190,21 @@ func (b *Block) ID() string {
 }
 
 func (b *Block) SetID(id string) {
+	oldID := b.id
 	b.id = id
+	b.changed(EventIDChanged, "", oldID, id)
+}
+
+// Content returns the text content of the block
+func (b *Block) Content() string {
+	return b.content
+}
+
+// SetContent sets the text content of the block
+func (b *Block) SetContent(content string) {
+	oldContent := b.content
+	b.content = content
+	b.changed(EventContentChanged, "", oldContent, content)
 }
 
 func (b *Block) HasParameter(key string) bool {
@@ -142,22 +217,28 @@ func (b *Block) Parameters() map[string]string {
 }
 
 func (b *Block) SetParameters(parameters map[string]string) {
+	oldParameters := b.parameters
 	b.parameters = parameters
+	b.changed(EventParametersChanged, "", oldParameters, parameters)
 }
 
+// Parameter returns the value of the parameter, or the default declared
+// by the schema registered for the block type (see RegisterSchema)
+// if the parameter is not set
 func (b *Block) Parameter(key string) string {
-	if value, ok := b.parameters[key]; ok {
-		return value
-	}
-
-	return ""
+	return resolveParameter(b.parameters, b.blockType, key)
 }
 
 func (b *Block) SetParameter(key, value string) {
 	if b.parameters == nil {
 		b.parameters = map[string]string{}
 	}
+	var oldValue any
+	if old, ok := b.parameters[key]; ok {
+		oldValue = old
+	}
 	b.parameters[key] = value
+	b.changed(EventParameterChanged, key, oldValue, value)
 }
 
 func (b *Block) Type() string {
@@ -165,7 +246,9 @@ func (b *Block) Type() string {
 }
 
 func (b *Block) SetType(blockType string) {
+	oldType := b.blockType
 	b.blockType = blockType
+	b.changed(EventTypeChanged, "", oldType, blockType)
 }
 
 func (b *Block) ToMap() map[string]interface{} {
@@ -178,6 +261,7 @@ func (b *Block) ToMap() map[string]interface{} {
 	return map[string]any{
 		"id":         b.ID(),
 		"type":       b.Type(),
+		"content":    b.Content(),
 		"parameters": b.Parameters(),
 		"children":   childrenMap,
 	}
@@ -207,31 +291,78 @@ func (b *Block) ToJsonPretty() (string, error) {
 	return string(jsonBytes), nil
 }
 
-func (b *Block) ToJsonObject() blockJsonObject {
-	parameters := b.Parameters()
+func (b *Block) ToJsonObject() BlockJsonObject {
+	return BlockToJsonObject(b)
+}
+
+// BlockJsonObject is the JSON representation of a block,
+// the children are serialized with their own ToJsonObject
+type BlockJsonObject struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+
+	// Content is the text content of the block
+	Content string `json:"content"`
+
+	Parameters map[string]string `json:"parameters"`
+	Children   []BlockJsonObject `json:"children"`
+}
+
+// BlockToJsonObject returns the JSON representation of any BlockInterface
+// implementation, using only its interface methods. Custom block types can
+// use it to implement ToJsonObject. The parameters are copied,
+// and nil children are skipped
+func BlockToJsonObject(block BlockInterface) BlockJsonObject {
+	parameters := maps.Clone(block.Parameters())
 	if len(parameters) < 1 {
 		parameters = make(map[string]string)
 	}
 
-	childrenJsonObject := make([]blockJsonObject, 0)
+	childrenJsonObject := make([]BlockJsonObject, 0)
+
+	for _, child := range block.Children() {
+		if child == nil {
+			continue
+		}
 
-	for _, child := range b.Children() {
-		childBlock := child.(*Block)
-		childrenJsonObject = append(childrenJsonObject, childBlock.ToJsonObject())
+		childrenJsonObject = append(childrenJsonObject, child.ToJsonObject())
 	}
 
-	return blockJsonObject{
-		ID:         b.ID(),
-		Type:       b.Type(),
+	return BlockJsonObject{
+		ID:         block.ID(),
+		Type:       block.Type(),
+		Content:    block.Content(),
 		Parameters: parameters,
 		Children:   childrenJsonObject,
 	}
 }
 
-type blockJsonObject struct {
-	ID         string            `json:"id"`
-	Type       string            `json:"type"`
-	Content    string            `json:"content"`
-	Parameters map[string]string `json:"parameters"`
-	Children   []blockJsonObject `json:"children"`
+// == OBSERVER ================================================================
+
+func (b *Block) setObserver(observer *TreeObserver) {
+	b.observer = observer
+}
+
+func (b *Block) treeObserver() *TreeObserver {
+	return b.observer
+}
+
+// changed notifies the observer of the tree, if any, of a change
+func (b *Block) changed(kind EventKind, key string, oldValue any, newValue any) {
+	if b.observer == nil {
+		return
+	}
+
+	b.observer.notify(Event{Kind: kind, Block: b, Key: key, OldValue: oldValue, NewValue: newValue})
+}
+
+// childrenChanged observes the new children, and notifies
+// the observer of the tree, if any, of the change
+func (b *Block) childrenChanged(oldChildren []BlockInterface) {
+	if b.observer == nil {
+		return
+	}
+
+	b.observer.childrenChanged(oldChildren, b.children)
+	b.changed(EventChildrenChanged, "", oldChildren, b.children)
 }
@@ -16,6 +16,7 @@ func TestBlock_ToMap(t *testing.T) {
 	block2 := NewBlock()
 	block2.SetID("2")
 	block2.SetType("block2")
+	block2.SetContent("Hello")
 	block2.SetParameter("key2", "value2")
 
 	block3 := NewBlock()
@@ -37,17 +38,20 @@ func TestBlock_ToMap(t *testing.T) {
 			want: map[string]interface{}{
 				"id":         "1",
 				"type":       "block1",
+				"content":    "",
 				"parameters": map[string]string{"key": "value"},
 				"children": []map[string]interface{}{
 					{
 						"id":         "2",
 						"type":       "block2",
+						"content":    "Hello",
 						"parameters": map[string]string{"key2": "value2"},
 						"children":   []map[string]interface{}{},
 					},
 					{
 						"id":         "3",
 						"type":       "block3",
+						"content":    "",
 						"parameters": map[string]string{"key3": "value3"},
 						"children":   []map[string]interface{}{},
 					},
@@ -73,6 +77,7 @@ func TestBlock_ToJson(t *testing.T) {
 	block2 := NewBlock()
 	block2.SetID("2")
 	block2.SetType("block2")
+	block2.SetContent("Hello")
 	block2.SetParameter("key2", "value2")
 
 	block3 := NewBlock()
@@ -102,7 +107,7 @@ func TestBlock_ToJson(t *testing.T) {
 		{
 			name:    "Block_ToJson",
 			b:       block1,
-			want:    `{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[{"id":"2","type":"block2","content":"","parameters":{"key2":"value2"},"children":[]},{"id":"3","type":"block3","content":"","parameters":{"key3":"value3"},"children":[]}]}`,
+			want:    `{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[{"id":"2","type":"block2","content":"Hello","parameters":{"key2":"value2"},"children":[]},{"id":"3","type":"block3","content":"","parameters":{"key3":"value3"},"children":[]}]}`,
 			wantErr: false,
 		},
 	}
@@ -120,6 +125,51 @@ func TestBlock_ToJson(t *testing.T) {
 	}
 }
 
+// testSecretBlock hides the "secret" parameter from its JSON
+type testSecretBlock struct {
+	BlockInterface
+}
+
+func (b *testSecretBlock) ToJsonObject() BlockJsonObject {
+	jsonObject := BlockToJsonObject(b)
+	delete(jsonObject.Parameters, "secret")
+	return jsonObject
+}
+
+func TestBlock_ToJson_AnyBlockInterface(t *testing.T) {
+	image := &testWrapperBlock{NewBlockBuilder().WithID("image1").WithType("image").Build()}
+	secret := &testSecretBlock{NewBlockBuilder().WithID("form1").WithType("form").
+		WithParameters(map[string]string{"secret": "token", "action": "/send"}).
+		WithChildren([]BlockInterface{image}).
+		Build()}
+
+	page := NewBlockBuilder().WithID("page1").WithType("page").WithChildren([]BlockInterface{secret, nil}).Build()
+
+	got, err := page.ToJson()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"id":"page1","type":"page","content":"","parameters":{},"children":[{"id":"form1","type":"form","content":"","parameters":{"action":"/send"},"children":[{"id":"image1","type":"image","content":"","parameters":{},"children":[]}]}]}`
+
+	if got != want {
+		t.Errorf("ToJson() = %s, want %s", got, want)
+	}
+
+	if _, err := page.ToJsonPretty(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if secret.Parameter("secret") != "token" {
+		t.Error("ToJsonObject must not modify the parameters of the block")
+	}
+
+	if got := BlockToJsonObject(image); got.ID != "image1" || got.Type != "image" {
+		t.Errorf("BlockToJsonObject() = %+v", got)
+	}
+}
+
 func TestBlock_BlockInterfaceToBlock(t *testing.T) {
 	type test struct {
 		Block
@@ -156,9 +206,9 @@ func TestBlockFromJSON(t *testing.T) {
 				blockJson: `{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[]}`,
 			},
 			want: &Block{
-				id:        "1",
-				blockType: "block1",
-				// content:    "",
+				id:         "1",
+				blockType:  "block1",
+				content:    "",
 				parameters: map[string]string{"key": "value"},
 				children:   []BlockInterface{},
 			},
@@ -167,25 +217,25 @@ func TestBlockFromJSON(t *testing.T) {
 		{
 			name: "NewFromJSON",
 			args: args{
-				blockJson: `{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[{"id":"2","type":"block2","content":"","parameters":{"key2":"value2"},"children":[]},{"id":"3","type":"block3","content":"","parameters":{"key3":"value3"},"children":[]}]}`,
+				blockJson: `{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[{"id":"2","type":"block2","content":"Hello","parameters":{"key2":"value2"},"children":[]},{"id":"3","type":"block3","content":"","parameters":{"key3":"value3"},"children":[]}]}`,
 			},
 			want: &Block{
-				id:        "1",
-				blockType: "block1",
-				// content:    "",
+				id:         "1",
+				blockType:  "block1",
+				content:    "",
 				parameters: map[string]string{"key": "value"},
 				children: []BlockInterface{
 					&Block{
-						id:        "2",
-						blockType: "block2",
-						// content:    "",
+						id:         "2",
+						blockType:  "block2",
+						content:    "Hello",
 						parameters: map[string]string{"key2": "value2"},
 						children:   []BlockInterface{},
 					},
 					&Block{
-						id:        "3",
-						blockType: "block3",
-						// content:    "",
+						id:         "3",
+						blockType:  "block3",
+						content:    "",
 						parameters: map[string]string{"key3": "value3"},
 						children:   []BlockInterface{},
 					},
@@ -218,7 +268,7 @@ func TestBlockFromMap(t *testing.T) {
 			{
 				"id":         "2",
 				"type":       "block2",
-				"content":    "",
+				"content":    "Hello",
 				"parameters": map[string]string{"key2": "value2"},
 				"children":   []map[string]interface{}{},
 			},
@@ -241,22 +291,22 @@ func TestBlockFromMap(t *testing.T) {
 			name: "NewFromMap",
 			args: args,
 			want: &Block{
-				id:        "1",
-				blockType: "block1",
-				// content:    "",
+				id:         "1",
+				blockType:  "block1",
+				content:    "",
 				parameters: map[string]string{"key": "value"},
 				children: []BlockInterface{
 					&Block{
-						id:        "2",
-						blockType: "block2",
-						// content:    "",
+						id:         "2",
+						blockType:  "block2",
+						content:    "Hello",
 						parameters: map[string]string{"key2": "value2"},
 						children:   []BlockInterface{},
 					},
 					&Block{
-						id:        "3",
-						blockType: "block3",
-						// content:    "",
+						id:         "3",
+						blockType:  "block3",
+						content:    "",
 						parameters: map[string]string{"key3": "value3"},
 						children:   []BlockInterface{},
 					},
@@ -283,6 +333,7 @@ func TestBlockBuilder(t *testing.T) {
 			want: &Block{
 				id:         "1",
 				blockType:  "block1",
+				content:    "Text",
 				parameters: map[string]string{},
 				children:   []BlockInterface{},
 			},
@@ -290,7 +341,7 @@ func TestBlockBuilder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewBlockBuilder().WithID("1").WithType("block1").Build()
+			got := NewBlockBuilder().WithID("1").WithType("block1").WithContent("Text").Build()
 			if diff := cmp.Diff(got, tt.want, cmp.AllowUnexported(Block{})); diff != "" {
 				t.Log(diff)
 				t.Errorf("BlockBuilder() = %v, want %v", got, tt.want)
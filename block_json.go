@@ -0,0 +1,393 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var _ json.Marshaler = Block{}
+var _ json.Unmarshaler = (*Block)(nil)
+
+// MarshalJSON implements json.Marshaler, using the same format as ToJson
+//
+// It has a value receiver, so that blocks are marshaled also
+// when held by value in non-addressable structs
+func (b Block) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.ToJsonObject())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, using the same format as ToJson
+//
+// The fields are set with the setters of the block, so an observed block
+// stays observed, and its observer is notified of the changes.
+// Returns a *DecodeError with the path of the offending field,
+// if the JSON is not a valid block
+func (b *Block) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	block, err := decodeBlockJson(data, "$", 1, &decodeState{})
+
+	if err != nil {
+		return err
+	}
+
+	b.SetID(block.id)
+	b.SetType(block.blockType)
+	b.SetContent(block.content)
+	b.SetParameters(block.parameters)
+	b.SetChildren(block.children)
+
+	return nil
+}
+
+// decodeBlockJson decodes a block and its children from JSON
+//
+// The JSON is read as a stream of tokens, in a single pass, so the time
+// taken is linear in the size of the input
+//
+// Parameters:
+// - data - the JSON of the block
+// - path - the JSON path of the block, used in errors
+// - depth - the depth of the block, the root block is at depth 1
+// - state - the decode state, enforcing the decode limits
+//
+// Returns:
+// - *Block - the block
+// - error - a *DecodeError if the JSON is not a valid block,
+// or a *DecodeLimitError if a decode limit is exceeded
+func decodeBlockJson(data []byte, path string, depth int, state *decodeState) (*Block, error) {
+	d := newBlockDecoder(data, state)
+
+	token, err := d.token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := d.decodeBlock(token, path, depth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.end(); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// == BLOCK DECODER ===========================================================
+
+// blockDecoder decodes blocks from a stream of JSON tokens,
+// enforcing the decode limits of the state
+type blockDecoder struct {
+	decoder *json.Decoder
+	state   *decodeState
+}
+
+func newBlockDecoder(data []byte, state *decodeState) *blockDecoder {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return &blockDecoder{decoder: decoder, state: state}
+}
+
+// token returns the next token, the end of the input is io.ErrUnexpectedEOF
+func (d *blockDecoder) token() (json.Token, error) {
+	token, err := d.decoder.Token()
+
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return token, err
+}
+
+// end returns an error if there is data after the decoded value
+func (d *blockDecoder) end() error {
+	if _, err := d.decoder.Token(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+
+		return errors.New("invalid JSON: data after the top-level value")
+	}
+
+	return nil
+}
+
+// decodeBlock decodes the block starting with the token
+func (d *blockDecoder) decodeBlock(token json.Token, path string, depth int) (*Block, error) {
+	if token != json.Delim('{') {
+		return nil, invalidFieldType(path, "object", token)
+	}
+
+	if err := d.state.enterBlock(path, depth); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+	parameters := map[string]string{}
+	children := []BlockInterface{}
+
+	for d.decoder.More() {
+		keyToken, err := d.token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := keyToken.(string) // object keys are always strings
+
+		value, err := d.token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "id", "type", "content":
+			fields[key] = value
+		case "parameters":
+			parameters, err = d.decodeParameters(value, path+".parameters")
+		case "children":
+			children, err = d.decodeBlockList(value, path+".children", depth+1)
+		default:
+			err = d.skip(value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the closing brace
+	if _, err := d.token(); err != nil {
+		return nil, err
+	}
+
+	id, blockType, content, err := blockStringFields(fields, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.state.checkFields(path, content, parameters); err != nil {
+		return nil, err
+	}
+
+	return &Block{
+		id:         id,
+		blockType:  blockType,
+		content:    content,
+		parameters: parameters,
+		children:   children,
+	}, nil
+}
+
+// decodeParameters decodes the parameters object starting with the token,
+// null is an empty object
+func (d *blockDecoder) decodeParameters(token json.Token, path string) (map[string]string, error) {
+	parameters := map[string]string{}
+
+	if token == nil {
+		return parameters, nil
+	}
+
+	if token != json.Delim('{') {
+		return nil, invalidFieldType(path, "object", token)
+	}
+
+	for d.decoder.More() {
+		keyToken, err := d.token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := keyToken.(string)
+
+		value, err := d.token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		parameters[key], err = blockParameterValue(value, path+"."+key)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := d.token(); err != nil {
+		return nil, err
+	}
+
+	return parameters, nil
+}
+
+// decodeBlockList decodes the array of blocks starting with the token,
+// null is an empty array
+func (d *blockDecoder) decodeBlockList(token json.Token, path string, depth int) ([]BlockInterface, error) {
+	blocks := []BlockInterface{}
+
+	if token == nil {
+		return blocks, nil
+	}
+
+	if token != json.Delim('[') {
+		return nil, invalidFieldType(path, "array", token)
+	}
+
+	for index := 0; d.decoder.More(); index++ {
+		blockToken, err := d.token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		block, err := d.decodeBlock(blockToken, fmt.Sprintf("%s[%d]", path, index), depth)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	if _, err := d.token(); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// skip skips the value starting with the token, i.e. an unknown field
+func (d *blockDecoder) skip(token json.Token) error {
+	if token != json.Delim('{') && token != json.Delim('[') {
+		return nil
+	}
+
+	for nesting := 1; nesting > 0; {
+		token, err := d.token()
+
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			nesting++
+		case json.Delim('}'), json.Delim(']'):
+			nesting--
+		}
+	}
+
+	return nil
+}
+
+// == FIELD CHECKS ============================================================
+
+// The field checks are shared by the JSON decoder and ConvertMapToBlock,
+// so that both accept the same input and report the same errors
+
+// blockStringFields returns the id, type and content of a block from
+// its fields, the id and type are required, the content is optional
+func blockStringFields(fields map[string]any, path string) (id string, blockType string, content string, err error) {
+	if id, err = blockStringField(fields["id"], path+".id", true); err != nil {
+		return "", "", "", err
+	}
+
+	if blockType, err = blockStringField(fields["type"], path+".type", true); err != nil {
+		return "", "", "", err
+	}
+
+	if content, err = blockStringField(fields["content"], path+".content", false); err != nil {
+		return "", "", "", err
+	}
+
+	return id, blockType, content, nil
+}
+
+// blockStringField checks a string field of a block, a missing or null field
+// is an ErrMissingField if it is required, or an empty string otherwise
+func blockStringField(value any, path string, required bool) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case nil:
+		if required {
+			return "", &DecodeError{Path: path, Err: ErrMissingField}
+		}
+		return "", nil
+	default:
+		return "", invalidFieldType(path, "string", value)
+	}
+}
+
+// blockParameterValue checks the value of a parameter, null is an empty string
+func blockParameterValue(value any, path string) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", nil
+	default:
+		return "", invalidFieldType(path, "string", value)
+	}
+}
+
+// invalidFieldType returns the DecodeError of a value of the wrong type
+func invalidFieldType(path string, expected string, value any) *DecodeError {
+	return &DecodeError{Path: path, Err: fmt.Errorf("%w: expected %s, got %s", ErrInvalidFieldType, expected, jsonTypeName(value))}
+}
+
+// jsonTypeName returns the JSON type of a decoded value, or of the JSON token
+func jsonTypeName(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any, []map[string]any:
+		return "array"
+	case map[string]any, map[string]string:
+		return "object"
+	case json.Delim:
+		if value == '[' {
+			return "array"
+		}
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// jsonErrorToDecodeError adds the path to type errors returned by encoding/json
+func jsonErrorToDecodeError(err error, path string) error {
+	var typeErr *json.UnmarshalTypeError
+
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	if typeErr.Field != "" {
+		path += "." + typeErr.Field
+	}
+
+	return &DecodeError{
+		Path: path,
+		Err:  fmt.Errorf("%w: expected %s, got %s", ErrInvalidFieldType, typeErr.Type, typeErr.Value),
+	}
+}
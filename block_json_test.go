@@ -0,0 +1,249 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlock_MarshalJSON(t *testing.T) {
+	child := NewBlock()
+	child.SetID("2")
+	child.SetType("block2")
+
+	block := NewBlock()
+	block.SetID("1")
+	block.SetType("block1")
+	block.SetParameter("key", "value")
+	block.AddChild(child)
+
+	type response struct {
+		Status string `json:"status"`
+		Block  Block  `json:"block"`
+		Ptr    *Block `json:"ptr"`
+	}
+
+	got, err := json.Marshal(response{
+		Status: "ok",
+		Block:  *(block.(*Block)),
+		Ptr:    child.(*Block),
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"status":"ok","block":{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[{"id":"2","type":"block2","content":"","parameters":{},"children":[]}]},"ptr":{"id":"2","type":"block2","content":"","parameters":{},"children":[]}}`
+
+	if string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+
+	toJson, err := block.ToJson()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	marshaled, err := json.Marshal(block)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(marshaled) != toJson {
+		t.Errorf("json.Marshal() = %s, want same as ToJson() %s", marshaled, toJson)
+	}
+}
+
+func TestBlock_UnmarshalJSON(t *testing.T) {
+	type request struct {
+		Block Block  `json:"block"`
+		Ptr   *Block `json:"ptr"`
+		Null  *Block `json:"null"`
+	}
+
+	data := `{"block":{"id":"1","type":"block1","parameters":{"key":"value"},"children":[{"id":"2","type":"block2"}]},"ptr":{"id":"3","type":"block3"},"null":null}`
+
+	got := request{}
+
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := request{
+		Block: Block{
+			id:         "1",
+			blockType:  "block1",
+			parameters: map[string]string{"key": "value"},
+			children: []BlockInterface{
+				&Block{
+					id:         "2",
+					blockType:  "block2",
+					parameters: map[string]string{},
+					children:   []BlockInterface{},
+				},
+			},
+		},
+		Ptr: &Block{
+			id:         "3",
+			blockType:  "block3",
+			parameters: map[string]string{},
+			children:   []BlockInterface{},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBlock_UnmarshalJSONErrors(t *testing.T) {
+	block := &Block{}
+
+	err := json.Unmarshal([]byte(`{"id":"1","type":"a","children":[{"id":"2"}]}`), block)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+
+	if decodeErr.Path != "$.children[0].type" || !errors.Is(err, ErrMissingField) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	err = json.Unmarshal([]byte(`{"id":"1","type":"a","parameters":{"width":1}}`), block)
+
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+
+	if decodeErr.Path != "$.parameters.width" || !errors.Is(err, ErrInvalidFieldType) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestBlock_UnmarshalJSONKeepsTheObserver(t *testing.T) {
+	block := NewBlock().(*Block)
+	observer := ObserveTree(block)
+
+	events := 0
+	observer.Subscribe(EventFilter{}, func(Event) { events++ })
+
+	if err := json.Unmarshal([]byte(`{"id":"1","type":"a","children":[{"id":"2","type":"b"}]}`), block); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if events == 0 {
+		t.Error("unmarshaling an observed block must notify the observer")
+	}
+
+	events = 0
+	block.SetType("c")
+	block.Children()[0].SetType("d")
+
+	if events != 2 {
+		t.Errorf("events after unmarshaling = %d, want 2", events)
+	}
+}
+
+func TestNewBlockFromJson_MatchesConvertMapToBlock(t *testing.T) {
+	inputs := []string{
+		`{"id":"1","type":"a","content":"x","parameters":{"k":"v"},"children":[{"id":"2","type":"b"}]}`,
+		`{"id":"1","type":"a","parameters":{"k":null}}`,
+		`{"id":"1","type":"a","content":null,"parameters":null,"children":null}`,
+		`{"id":null,"type":"a"}`,
+		`{"type":"a"}`,
+		`{"id":5,"type":"a"}`,
+		`{"id":"1","type":true}`,
+		`{"id":"1","type":"a","content":["x"]}`,
+		`{"id":"1","type":"a","parameters":{"width":100}}`,
+		`{"id":"1","type":"a","parameters":{"k":{}}}`,
+		`{"id":"1","type":"a","parameters":[]}`,
+		`{"id":"1","type":"a","children":{}}`,
+		`{"id":"1","type":"a","children":["2"]}`,
+		`{"id":"1","type":"a","children":[{"id":"2","type":"b","parameters":{"x":false}}]}`,
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			blockMap := map[string]any{}
+
+			if err := json.Unmarshal([]byte(input), &blockMap); err != nil {
+				t.Fatal(err)
+			}
+
+			fromJson, jsonErr := NewBlockFromJson(input)
+			fromMap, mapErr := ConvertMapToBlock(blockMap)
+
+			if fmt.Sprint(jsonErr) != fmt.Sprint(mapErr) {
+				t.Fatalf("NewBlockFromJson() error = %v, ConvertMapToBlock() error = %v", jsonErr, mapErr)
+			}
+
+			if jsonErr == nil && mustToJson(t, fromJson) != mustToJson(t, fromMap) {
+				t.Errorf("NewBlockFromJson() = %s, ConvertMapToBlock() = %s", mustToJson(t, fromJson), mustToJson(t, fromMap))
+			}
+		})
+	}
+}
+
+func TestBlock_JSONRoundTrip(t *testing.T) {
+	block, err := NewBlockFromJson(`{"id":"1","type":"block1","content":"","parameters":{"key":"value"},"children":[{"id":"2","type":"block2","content":"","parameters":{},"children":[]}]}`)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(block)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := &Block{}
+
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(BlockInterface(got), block) {
+		t.Errorf("round trip = %+v, want %+v", got, block)
+	}
+}
+
+func TestNewBlockFromJson_DeeplyNestedIsLinear(t *testing.T) {
+	const depth = 4000
+
+	data := strings.Repeat(`{"id":"b","type":"t","children":[`, depth-1) +
+		`{"id":"leaf","type":"t"}` +
+		strings.Repeat(`]}`, depth-1)
+
+	start := time.Now()
+
+	block, err := NewBlockFromJson(data)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// re-parsing each subtree per level takes minutes at this depth
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("decoding %d KB took %v", len(data)/1024, elapsed)
+	}
+
+	levels := 0
+
+	for level, b := range WithDepth(block) {
+		if b.ID() == "leaf" {
+			levels = level + 1
+		}
+	}
+
+	if levels != depth {
+		t.Errorf("decoded depth = %d, want %d", levels, depth)
+	}
+}
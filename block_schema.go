@@ -0,0 +1,368 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrParameterRequired is returned when a required parameter is missing
+var ErrParameterRequired = errors.New("parameter is required")
+
+// ErrParameterInvalid is returned when a parameter value does not match its schema
+var ErrParameterInvalid = errors.New("parameter is invalid")
+
+// ErrContentRequired is returned when a block requiring content has none
+var ErrContentRequired = errors.New("content is required")
+
+// ErrContentInvalid is returned when the content does not match its schema
+var ErrContentInvalid = errors.New("content is invalid")
+
+// ParameterKind is the kind of value a parameter holds
+type ParameterKind string
+
+const (
+	ParameterKindString ParameterKind = "string"
+	ParameterKindInt    ParameterKind = "int"
+	ParameterKindBool   ParameterKind = "bool"
+	ParameterKindEnum   ParameterKind = "enum"
+	ParameterKindURL    ParameterKind = "url"
+	ParameterKindColor  ParameterKind = "color"
+	ParameterKindDate   ParameterKind = "date"
+)
+
+// ParameterDateLayout is the layout of ParameterKindDate values
+const ParameterDateLayout = "2006-01-02"
+
+var colorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ParameterSchema declares the rules for a single block parameter
+type ParameterSchema struct {
+	// Name is the parameter key
+	Name string
+
+	// Kind is the kind of the value, defaults to ParameterKindString
+	Kind ParameterKind
+
+	// Required parameters must be set on the block,
+	// the Default is not used to satisfy this rule
+	Required bool
+
+	// Default is returned when the parameter is not set, by Block.Parameter
+	// for schemas registered with RegisterSchema, and by BlockValidator.Parameter
+	// for schemas added with BlockValidator.AddSchema
+	Default string
+
+	// Enum lists the allowed values, required for ParameterKindEnum
+	Enum []string
+
+	// Pattern is an optional regular expression the value must match
+	Pattern string
+
+	// MinLength is the minimum length of the value in characters, 0 for no minimum
+	MinLength int
+
+	// MaxLength is the maximum length of the value in characters, 0 for no maximum
+	MaxLength int
+}
+
+// BlockSchema declares the parameters of a block type
+type BlockSchema struct {
+	// Type is the block type the schema applies to
+	Type string
+
+	// Parameters are the declared parameters,
+	// parameters not listed here are not validated
+	Parameters []ParameterSchema
+
+	// ContentRequired blocks must have a non-empty content
+	ContentRequired bool
+
+	// ContentMaxLength is the maximum length of the content
+	// in characters, 0 for no maximum
+	ContentMaxLength int
+}
+
+// Compile compiles the schema into a Validator
+//
+// Returns an error if the schema itself is invalid, i.e. a pattern
+// does not compile, an enum has no values, or a default value does
+// not satisfy the rules of its parameter
+func (s BlockSchema) Compile() (Validator, error) {
+	compiled := make([]compiledParameterSchema, 0, len(s.Parameters))
+
+	for _, parameter := range s.Parameters {
+		c, err := compileParameterSchema(parameter)
+
+		if err != nil {
+			return nil, fmt.Errorf("schema %q: %w", s.Type, err)
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	return func(block BlockInterface) error {
+		errs := []error{}
+
+		if err := s.validateContent(block.Content()); err != nil {
+			errs = append(errs, err)
+		}
+
+		for _, parameter := range compiled {
+			if err := parameter.validate(block); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	}, nil
+}
+
+func (s BlockSchema) validateContent(content string) error {
+	if content == "" {
+		if s.ContentRequired {
+			return ErrContentRequired
+		}
+
+		return nil
+	}
+
+	if s.ContentMaxLength > 0 && utf8.RuneCountInString(content) > s.ContentMaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrContentInvalid, s.ContentMaxLength)
+	}
+
+	return nil
+}
+
+// Default returns the declared default value of a parameter
+func (s BlockSchema) Default(key string) (string, bool) {
+	for _, parameter := range s.Parameters {
+		if parameter.Name == key && parameter.Default != "" {
+			return parameter.Default, true
+		}
+	}
+
+	return "", false
+}
+
+// AddSchema compiles the schema and registers it as the validator
+// for the schema block type
+//
+// The defaults of the schema are scoped to the validator, and are returned
+// by BlockValidator.Parameter. Use RegisterSchema to make Block.Parameter
+// return them too
+func (v *BlockValidator) AddSchema(schema BlockSchema) error {
+	validator, err := schema.Compile()
+
+	if err != nil {
+		return err
+	}
+
+	v.Add(schema.Type, validator)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.schemas[schema.Type] = schema
+
+	return nil
+}
+
+// Parameter returns the value of the parameter of the block, or the default
+// declared by the schema added for the block type (see AddSchema)
+// if the parameter is not set
+func (v *BlockValidator) Parameter(block BlockInterface, key string) string {
+	if !block.HasParameter(key) {
+		v.mu.RLock()
+		schema := v.schemas[block.Type()]
+		v.mu.RUnlock()
+
+		if value, ok := schema.Default(key); ok {
+			return value
+		}
+	}
+
+	return block.Parameter(key)
+}
+
+// == SCHEMA REGISTRY =========================================================
+
+// schemaRegistry is the package wide registry of block schemas,
+// used to resolve parameter defaults
+var schemaRegistry = struct {
+	mu      sync.RWMutex
+	schemas map[string]BlockSchema
+}{
+	schemas: map[string]BlockSchema{},
+}
+
+// RegisterSchema registers the schema package wide, so that Block.Parameter
+// returns the declared defaults for parameters which are not set.
+// The last schema registered for a block type is used
+//
+// Defaults are resolved at read time only, by Parameter (and the typed
+// parameter helpers). They are not stored in the block: HasParameter
+// returns false for them, and Parameters, ToMap and ToJson do not include them
+//
+// The schema is compiled first, and an error is returned if it is invalid.
+// BlockValidator.AddSchema does not register the schema package wide,
+// register it with both to validate blocks and resolve the defaults
+func RegisterSchema(schema BlockSchema) error {
+	if _, err := schema.Compile(); err != nil {
+		return err
+	}
+
+	schemaRegistry.mu.Lock()
+	defer schemaRegistry.mu.Unlock()
+	schemaRegistry.schemas[schema.Type] = schema
+
+	return nil
+}
+
+// UnregisterSchema removes the package wide schema for the block type
+func UnregisterSchema(blockType string) {
+	schemaRegistry.mu.Lock()
+	defer schemaRegistry.mu.Unlock()
+	delete(schemaRegistry.schemas, blockType)
+}
+
+// SchemaFor returns the package wide schema registered for the block type
+func SchemaFor(blockType string) (BlockSchema, bool) {
+	schemaRegistry.mu.RLock()
+	defer schemaRegistry.mu.RUnlock()
+	schema, exists := schemaRegistry.schemas[blockType]
+	return schema, exists
+}
+
+// resolveParameter returns the value of the parameter, or the default
+// declared by the package wide schema of the block type (see RegisterSchema)
+// if the parameter is not set. It is the Parameter of the block implementations
+func resolveParameter(parameters map[string]string, blockType string, key string) string {
+	if value, ok := parameters[key]; ok {
+		return value
+	}
+
+	schema, exists := SchemaFor(blockType)
+
+	if !exists {
+		return ""
+	}
+
+	value, _ := schema.Default(key)
+
+	return value
+}
+
+// == COMPILED SCHEMA =========================================================
+
+type compiledParameterSchema struct {
+	ParameterSchema
+	pattern *regexp.Regexp
+}
+
+func compileParameterSchema(parameter ParameterSchema) (compiledParameterSchema, error) {
+	compiled := compiledParameterSchema{ParameterSchema: parameter}
+
+	if compiled.Name == "" {
+		return compiled, errors.New("parameter name is required")
+	}
+
+	if compiled.Kind == "" {
+		compiled.Kind = ParameterKindString
+	}
+
+	switch compiled.Kind {
+	case ParameterKindString, ParameterKindInt, ParameterKindBool, ParameterKindURL, ParameterKindColor, ParameterKindDate:
+	case ParameterKindEnum:
+		if len(compiled.Enum) < 1 {
+			return compiled, fmt.Errorf("parameter %q: enum values are required", compiled.Name)
+		}
+	default:
+		return compiled, fmt.Errorf("parameter %q: unknown kind %q", compiled.Name, compiled.Kind)
+	}
+
+	if compiled.Pattern != "" {
+		pattern, err := regexp.Compile(compiled.Pattern)
+
+		if err != nil {
+			return compiled, fmt.Errorf("parameter %q: %w", compiled.Name, err)
+		}
+
+		compiled.pattern = pattern
+	}
+
+	if compiled.Default != "" {
+		if err := compiled.validateValue(compiled.Default); err != nil {
+			return compiled, fmt.Errorf("default: %w", err)
+		}
+	}
+
+	return compiled, nil
+}
+
+func (p compiledParameterSchema) validate(block BlockInterface) error {
+	if !block.HasParameter(p.Name) {
+		if p.Required {
+			return fmt.Errorf("parameter %q: %w", p.Name, ErrParameterRequired)
+		}
+
+		return nil
+	}
+
+	return p.validateValue(block.Parameters()[p.Name])
+}
+
+func (p compiledParameterSchema) validateValue(value string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("parameter %q: %w: %s", p.Name, ErrParameterInvalid, reason)
+	}
+
+	length := utf8.RuneCountInString(value)
+
+	if p.MinLength > 0 && length < p.MinLength {
+		return invalid(fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return invalid(fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+
+	if p.pattern != nil && !p.pattern.MatchString(value) {
+		return invalid("must match pattern " + p.Pattern)
+	}
+
+	switch p.Kind {
+	case ParameterKindInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return invalid("must be an integer")
+		}
+	case ParameterKindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return invalid("must be a boolean")
+		}
+	case ParameterKindEnum:
+		if !slices.Contains(p.Enum, value) {
+			return invalid(fmt.Sprintf("must be one of %v", p.Enum))
+		}
+	case ParameterKindURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return invalid("must be an absolute URL")
+		}
+	case ParameterKindColor:
+		if !colorRegexp.MatchString(value) {
+			return invalid("must be a hex color")
+		}
+	case ParameterKindDate:
+		if _, err := time.Parse(ParameterDateLayout, value); err != nil {
+			return invalid("must be a date in the format " + ParameterDateLayout)
+		}
+	}
+
+	return nil
+}
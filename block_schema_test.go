@@ -0,0 +1,313 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestImageSchema() BlockSchema {
+	return BlockSchema{
+		Type: "image",
+		Parameters: []ParameterSchema{
+			{Name: "src", Kind: ParameterKindURL, Required: true},
+			{Name: "alt", MaxLength: 10},
+			{Name: "width", Kind: ParameterKindInt, Default: "100"},
+			{Name: "lazy", Kind: ParameterKindBool},
+			{Name: "align", Kind: ParameterKindEnum, Enum: []string{"left", "center", "right"}, Default: "left"},
+			{Name: "border", Kind: ParameterKindColor},
+			{Name: "published", Kind: ParameterKindDate},
+			{Name: "code", Pattern: `^[a-z]+$`, MinLength: 2},
+		},
+	}
+}
+
+func TestBlockSchema_Compile(t *testing.T) {
+	validator, err := newTestImageSchema().Compile()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		wantErr    error
+		errMessage string
+	}{
+		{
+			name:       "valid",
+			parameters: map[string]string{"src": "https://example.com/a.png", "alt": "A", "width": "10", "lazy": "true", "align": "right", "border": "#ff0000", "published": "2024-02-29", "code": "ab"},
+		},
+		{
+			name:       "missing required",
+			parameters: map[string]string{},
+			wantErr:    ErrParameterRequired,
+			errMessage: `parameter "src": parameter is required`,
+		},
+		{
+			name:       "invalid url",
+			parameters: map[string]string{"src": "example.com"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be an absolute URL",
+		},
+		{
+			name:       "too long",
+			parameters: map[string]string{"src": "https://example.com", "alt": "more than ten"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be at most 10 characters",
+		},
+		{
+			name:       "invalid int",
+			parameters: map[string]string{"src": "https://example.com", "width": "wide"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be an integer",
+		},
+		{
+			name:       "invalid bool",
+			parameters: map[string]string{"src": "https://example.com", "lazy": "maybe"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be a boolean",
+		},
+		{
+			name:       "invalid enum",
+			parameters: map[string]string{"src": "https://example.com", "align": "top"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be one of [left center right]",
+		},
+		{
+			name:       "invalid color",
+			parameters: map[string]string{"src": "https://example.com", "border": "red"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be a hex color",
+		},
+		{
+			name:       "invalid date",
+			parameters: map[string]string{"src": "https://example.com", "published": "2023-02-29"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be a date",
+		},
+		{
+			name:       "pattern mismatch",
+			parameters: map[string]string{"src": "https://example.com", "code": "AB"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must match pattern",
+		},
+		{
+			name:       "too short",
+			parameters: map[string]string{"src": "https://example.com", "code": "a"},
+			wantErr:    ErrParameterInvalid,
+			errMessage: "must be at least 2 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := NewBlock()
+			block.SetType("image")
+			block.SetParameters(tt.parameters)
+
+			err := validator(block)
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if !strings.Contains(err.Error(), tt.errMessage) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.errMessage)
+			}
+		})
+	}
+}
+
+func TestBlockSchema_CompileAllErrors(t *testing.T) {
+	validator, err := newTestImageSchema().Compile()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	block := NewBlock()
+	block.SetType("image")
+	block.SetParameter("width", "wide")
+
+	err = validator(block)
+
+	if !errors.Is(err, ErrParameterRequired) || !errors.Is(err, ErrParameterInvalid) {
+		t.Errorf("expected both required and invalid errors, got %v", err)
+	}
+}
+
+func TestBlockSchema_CompileInvalidSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema BlockSchema
+	}{
+		{
+			name:   "missing name",
+			schema: BlockSchema{Type: "a", Parameters: []ParameterSchema{{}}},
+		},
+		{
+			name:   "unknown kind",
+			schema: BlockSchema{Type: "a", Parameters: []ParameterSchema{{Name: "x", Kind: "float"}}},
+		},
+		{
+			name:   "enum without values",
+			schema: BlockSchema{Type: "a", Parameters: []ParameterSchema{{Name: "x", Kind: ParameterKindEnum}}},
+		},
+		{
+			name:   "invalid pattern",
+			schema: BlockSchema{Type: "a", Parameters: []ParameterSchema{{Name: "x", Pattern: "("}}},
+		},
+		{
+			name:   "invalid default",
+			schema: BlockSchema{Type: "a", Parameters: []ParameterSchema{{Name: "x", Kind: ParameterKindInt, Default: "one"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.schema.Compile(); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestBlockValidator_AddSchema(t *testing.T) {
+	validator := NewBlockValidator()
+
+	if err := validator.AddSchema(newTestImageSchema()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	block := NewBlock()
+	block.SetType("image")
+
+	if err := validator.Validate(block); !errors.Is(err, ErrParameterRequired) {
+		t.Errorf("expected ErrParameterRequired, got %v", err)
+	}
+
+	if got := validator.Parameter(block, "width"); got != "100" {
+		t.Errorf("Parameter(width) = %q, want the schema default", got)
+	}
+
+	if got := block.Parameter("width"); got != "" {
+		t.Errorf("AddSchema must not register the defaults package wide, Parameter(width) = %q", got)
+	}
+
+	if block.HasParameter("width") || mustToJson(t, block) != `{"id":"`+block.ID()+`","type":"image","content":"","parameters":{},"children":[]}` {
+		t.Error("defaults must be resolved at read time only, not stored in the block")
+	}
+
+	block.SetParameter("src", "https://example.com/a.png")
+
+	if err := validator.Validate(block); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBlockValidator_SchemaDefaultsAreScoped(t *testing.T) {
+	validator1 := NewBlockValidator()
+	validator2 := NewBlockValidator()
+
+	schema := BlockSchema{Type: "img", Parameters: []ParameterSchema{{Name: "w", Kind: ParameterKindInt, Default: "10"}}}
+
+	if err := validator1.AddSchema(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	schema.Parameters = []ParameterSchema{{Name: "w", Kind: ParameterKindInt, Default: "20"}}
+
+	if err := validator2.AddSchema(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	block := NewBlockBuilder().WithType("img").Build()
+
+	if got := validator1.Parameter(block, "w"); got != "10" {
+		t.Errorf("validator1.Parameter(w) = %q, want 10", got)
+	}
+
+	if got := validator2.Parameter(block, "w"); got != "20" {
+		t.Errorf("validator2.Parameter(w) = %q, want 20", got)
+	}
+
+	block.SetParameter("w", "30")
+
+	if got := validator1.Parameter(block, "w"); got != "30" {
+		t.Errorf("validator1.Parameter(w) = %q, want the set value 30", got)
+	}
+}
+
+func TestBlockSchema_Content(t *testing.T) {
+	validator, err := BlockSchema{Type: "heading", ContentRequired: true, ContentMaxLength: 5}.Compile()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	block := NewBlockBuilder().WithType("heading").Build()
+
+	if err := validator(block); !errors.Is(err, ErrContentRequired) {
+		t.Errorf("expected ErrContentRequired, got %v", err)
+	}
+
+	block.SetContent("Héllo")
+
+	if err := validator(block); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	block.SetContent("Hello!")
+
+	if err := validator(block); !errors.Is(err, ErrContentInvalid) {
+		t.Errorf("expected ErrContentInvalid, got %v", err)
+	}
+}
+
+func TestRegisterSchema_Defaults(t *testing.T) {
+	if err := RegisterSchema(newTestImageSchema()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer UnregisterSchema("image")
+
+	block := NewBlock()
+	block.SetType("image")
+
+	if got := block.Parameter("width"); got != "100" {
+		t.Errorf("Parameter(width) = %q, want default %q", got, "100")
+	}
+
+	if block.HasParameter("width") {
+		t.Error("HasParameter(width) must be false for a defaulted parameter")
+	}
+
+	block.SetParameter("width", "50")
+
+	if got := block.Parameter("width"); got != "50" {
+		t.Errorf("Parameter(width) = %q, want %q", got, "50")
+	}
+
+	other := NewBlock()
+	other.SetType("paragraph")
+
+	if got := other.Parameter("width"); got != "" {
+		t.Errorf("Parameter(width) = %q, want empty for a type without schema", got)
+	}
+
+	if err := RegisterSchema(BlockSchema{Type: "bad", Parameters: []ParameterSchema{{Name: "x", Pattern: "("}}}); err == nil {
+		t.Error("expected error registering an invalid schema")
+	}
+
+	if _, exists := SchemaFor("bad"); exists {
+		t.Error("invalid schema must not be registered")
+	}
+}
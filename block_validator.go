@@ -1,21 +1,112 @@
 // block_validator.go
 package ui
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
 
 // Validator validates a block
 type Validator func(block BlockInterface) error
 
+// ErrChildNotAllowed is returned when a block type is not allowed
+// as a child of its parent block type
+var ErrChildNotAllowed = errors.New("child not allowed")
+
+// ErrForbiddenAncestor is returned when a block is nested inside
+// a block type it is not allowed to be nested in
+var ErrForbiddenAncestor = errors.New("forbidden ancestor")
+
+// ErrTooFewChildren is returned when a block has less children
+// than the minimum allowed for its type
+var ErrTooFewChildren = errors.New("too few children")
+
+// ErrTooManyChildren is returned when a block has more children
+// than the maximum allowed for its type
+var ErrTooManyChildren = errors.New("too many children")
+
+// ContainmentRule declares how a block type can be nested
+type ContainmentRule struct {
+	// AllowedChildren lists the block types allowed as children,
+	// an empty list allows any child type
+	AllowedChildren []string
+
+	// ForbiddenAncestors lists the block types the block
+	// must not be nested in, at any depth
+	ForbiddenAncestors []string
+
+	// MinChildren is the minimum number of children, 0 for no minimum
+	MinChildren int
+
+	// MaxChildren is the maximum number of children, 0 for no maximum
+	MaxChildren int
+}
+
 // BlockValidator is a thread-safe registry of block validators
 type BlockValidator struct {
 	mu         sync.RWMutex
 	validators map[string]Validator
+	rules      map[string]ContainmentRule
+	schemas    map[string]BlockSchema
+}
+
+// ValidationError is a validation failure of a single block in a tree
+type ValidationError struct {
+	// BlockID is the ID of the invalid block
+	BlockID string
+
+	// BlockType is the type of the invalid block
+	BlockType string
+
+	// Path is the slash separated list of block IDs from the root
+	// to the invalid block, i.e. document1/page1/paragraph2
+	Path string
+
+	// Err is the error returned by the validator
+	Err error
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is the list of all validation failures in a tree
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+
+	for _, validationError := range e {
+		messages = append(messages, validationError.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap allows errors.Is and errors.As to match any of the validation errors
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+
+	for _, validationError := range e {
+		errs = append(errs, validationError)
+	}
+
+	return errs
 }
 
 // NewBlockValidator creates a new BlockValidator
 func NewBlockValidator() *BlockValidator {
 	return &BlockValidator{
 		validators: make(map[string]Validator),
+		rules:      make(map[string]ContainmentRule),
+		schemas:    make(map[string]BlockSchema),
 	}
 }
 
@@ -26,6 +117,16 @@ func (v *BlockValidator) Add(blockType string, validator Validator) {
 	v.validators[blockType] = validator
 }
 
+// AddContainmentRule registers the containment rule for a block type
+//
+// Containment rules are enforced by ValidateTree only, as they depend
+// on the position of the block in the tree
+func (v *BlockValidator) AddContainmentRule(blockType string, rule ContainmentRule) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[blockType] = rule
+}
+
 // Validate validates a block using its registered validator
 func (v *BlockValidator) Validate(block BlockInterface) error {
 	if block == nil {
@@ -42,3 +143,104 @@ func (v *BlockValidator) Validate(block BlockInterface) error {
 
 	return validator(block)
 }
+
+// ValidateTree validates the block and all of its descendants,
+// using the registered validators and containment rules
+//
+// Unlike Validate, it does not stop at the first failure. All failures
+// are collected and returned as ValidationErrors, in pre-order
+// (parents before children). Returns nil if the whole tree is valid
+func (v *BlockValidator) ValidateTree(root BlockInterface) error {
+	if root == nil {
+		return nil
+	}
+
+	errs := ValidationErrors{}
+
+	v.validateTree(root, []BlockInterface{}, &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateTree validates the block, then recurses into its children
+func (v *BlockValidator) validateTree(block BlockInterface, ancestors []BlockInterface, errs *ValidationErrors) {
+	blockErrs := []error{}
+
+	if err := v.Validate(block); err != nil {
+		blockErrs = append(blockErrs, err)
+	}
+
+	blockErrs = append(blockErrs, v.validateContainment(block, ancestors)...)
+
+	path := make([]string, 0, len(ancestors)+1)
+	for _, ancestor := range ancestors {
+		path = append(path, ancestor.ID())
+	}
+	path = append(path, block.ID())
+
+	if len(blockErrs) > 0 {
+		*errs = append(*errs, ValidationError{
+			BlockID:   block.ID(),
+			BlockType: block.Type(),
+			Path:      strings.Join(path, "/"),
+			Err:       errors.Join(blockErrs...),
+		})
+	}
+
+	ancestors = append(ancestors, block)
+
+	for _, child := range block.Children() {
+		if child == nil {
+			continue
+		}
+
+		v.validateTree(child, ancestors, errs)
+	}
+}
+
+// validateContainment checks the containment rules of the block type,
+// and of the parent block type
+func (v *BlockValidator) validateContainment(block BlockInterface, ancestors []BlockInterface) []error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	errs := []error{}
+
+	if len(ancestors) > 0 {
+		parent := ancestors[len(ancestors)-1]
+		parentRule, exists := v.rules[parent.Type()]
+
+		if exists && len(parentRule.AllowedChildren) > 0 && !slices.Contains(parentRule.AllowedChildren, block.Type()) {
+			errs = append(errs, fmt.Errorf("%w: %q in %q", ErrChildNotAllowed, block.Type(), parent.Type()))
+		}
+	}
+
+	rule, exists := v.rules[block.Type()]
+
+	if !exists {
+		return errs
+	}
+
+	for _, ancestor := range ancestors {
+		if slices.Contains(rule.ForbiddenAncestors, ancestor.Type()) {
+			errs = append(errs, fmt.Errorf("%w: %q in %q", ErrForbiddenAncestor, block.Type(), ancestor.Type()))
+			break
+		}
+	}
+
+	childCount := len(block.Children())
+
+	if rule.MinChildren > 0 && childCount < rule.MinChildren {
+		errs = append(errs, fmt.Errorf("%w: %d, minimum is %d", ErrTooFewChildren, childCount, rule.MinChildren))
+	}
+
+	if rule.MaxChildren > 0 && childCount > rule.MaxChildren {
+		errs = append(errs, fmt.Errorf("%w: %d, maximum is %d", ErrTooManyChildren, childCount, rule.MaxChildren))
+	}
+
+	return errs
+}
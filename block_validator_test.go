@@ -137,3 +137,219 @@ func TestBlockValidator_Concurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockValidator_ValidateTree(t *testing.T) {
+	validator := NewBlockValidator()
+	validator.Add("paragraph", func(block BlockInterface) error {
+		if block.Parameter("content") == "" {
+			return errors.New("content is required")
+		}
+		return nil
+	})
+	validator.Add("page", func(block BlockInterface) error {
+		if len(block.Children()) > 1 {
+			return errors.New("too many children")
+		}
+		return nil
+	})
+
+	paragraph1 := NewBlock()
+	paragraph1.SetID("paragraph1")
+	paragraph1.SetType("paragraph")
+
+	paragraph2 := NewBlock()
+	paragraph2.SetID("paragraph2")
+	paragraph2.SetType("paragraph")
+	paragraph2.SetParameter("content", "Hello")
+
+	paragraph3 := NewBlock()
+	paragraph3.SetID("paragraph3")
+	paragraph3.SetType("paragraph")
+
+	page := NewBlock()
+	page.SetID("page1")
+	page.SetType("page")
+	page.AddChildren([]BlockInterface{paragraph1, paragraph2, paragraph3})
+
+	document := NewBlock()
+	document.SetID("document1")
+	document.SetType("document")
+	document.AddChild(page)
+
+	err := validator.ValidateTree(document)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	want := []ValidationError{
+		{BlockID: "page1", BlockType: "page", Path: "document1/page1"},
+		{BlockID: "paragraph1", BlockType: "paragraph", Path: "document1/page1/paragraph1"},
+		{BlockID: "paragraph3", BlockType: "paragraph", Path: "document1/page1/paragraph3"},
+	}
+
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+
+	for i, w := range want {
+		if errs[i].BlockID != w.BlockID || errs[i].BlockType != w.BlockType || errs[i].Path != w.Path {
+			t.Errorf("error %d = %+v, want %+v", i, errs[i], w)
+		}
+	}
+
+	if errs[1].Error() != "document1/page1/paragraph1: content is required" {
+		t.Errorf("unexpected error message %q", errs[1].Error())
+	}
+}
+
+func TestBlockValidator_ValidateTreeValid(t *testing.T) {
+	validator := NewBlockValidator()
+	validator.Add("paragraph", func(BlockInterface) error { return nil })
+
+	paragraph := NewBlock()
+	paragraph.SetType("paragraph")
+
+	page := NewBlock()
+	page.AddChild(paragraph)
+
+	if err := validator.ValidateTree(page); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := validator.ValidateTree(nil); err != nil {
+		t.Errorf("unexpected error for nil block: %v", err)
+	}
+}
+
+func TestBlockValidator_ValidateTreeErrorsIs(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	validator := NewBlockValidator()
+	validator.Add("image", func(BlockInterface) error { return errInvalid })
+
+	image := NewBlock()
+	image.SetType("image")
+
+	page := NewBlock()
+	page.AddChild(image)
+
+	if err := validator.ValidateTree(page); !errors.Is(err, errInvalid) {
+		t.Errorf("expected errors.Is to match the validator error, got %v", err)
+	}
+}
+
+func TestBlockValidator_ContainmentRules(t *testing.T) {
+	validator := NewBlockValidator()
+	validator.AddContainmentRule("page", ContainmentRule{
+		AllowedChildren:    []string{"paragraph", "list"},
+		ForbiddenAncestors: []string{"paragraph"},
+	})
+	validator.AddContainmentRule("list", ContainmentRule{
+		AllowedChildren: []string{"item"},
+		MinChildren:     1,
+		MaxChildren:     2,
+	})
+
+	newTestBlock := func(id, blockType string, children ...BlockInterface) BlockInterface {
+		block := NewBlock()
+		block.SetID(id)
+		block.SetType(blockType)
+		block.SetChildren(children)
+		return block
+	}
+
+	tests := []struct {
+		name     string
+		root     BlockInterface
+		wantErrs map[string]error
+	}{
+		{
+			name: "valid",
+			root: newTestBlock("page1", "page",
+				newTestBlock("paragraph1", "paragraph"),
+				newTestBlock("list1", "list", newTestBlock("item1", "item")),
+			),
+		},
+		{
+			name: "child not allowed",
+			root: newTestBlock("page1", "page",
+				newTestBlock("image1", "image"),
+			),
+			wantErrs: map[string]error{"page1/image1": ErrChildNotAllowed},
+		},
+		{
+			name: "forbidden ancestor",
+			root: newTestBlock("paragraph1", "paragraph",
+				newTestBlock("div1", "div", newTestBlock("page1", "page")),
+			),
+			wantErrs: map[string]error{"paragraph1/div1/page1": ErrForbiddenAncestor},
+		},
+		{
+			name: "too few children",
+			root: newTestBlock("page1", "page",
+				newTestBlock("list1", "list"),
+			),
+			wantErrs: map[string]error{"page1/list1": ErrTooFewChildren},
+		},
+		{
+			name: "too many children",
+			root: newTestBlock("list1", "list",
+				newTestBlock("item1", "item"),
+				newTestBlock("item2", "item"),
+				newTestBlock("item3", "item"),
+			),
+			wantErrs: map[string]error{"list1": ErrTooManyChildren},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateTree(tt.root)
+
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.wantErrs), len(errs), errs)
+			}
+
+			for _, e := range errs {
+				wantErr, ok := tt.wantErrs[e.Path]
+				if !ok {
+					t.Errorf("unexpected error at %s: %v", e.Path, e.Err)
+					continue
+				}
+				if !errors.Is(e, wantErr) {
+					t.Errorf("error at %s = %v, want %v", e.Path, e.Err, wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockValidator_ContainmentRulesNotAppliedByValidate(t *testing.T) {
+	validator := NewBlockValidator()
+	validator.AddContainmentRule("list", ContainmentRule{MinChildren: 1})
+
+	list := NewBlock()
+	list.SetType("list")
+
+	if err := validator.Validate(list); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := validator.ValidateTree(list); !errors.Is(err, ErrTooFewChildren) {
+		t.Errorf("expected ErrTooFewChildren, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"maps"
+
+	"github.com/dracory/uid"
+)
+
+// CloneIDStrategy defines how Clone assigns IDs to the copied blocks
+type CloneIDStrategy int
+
+const (
+	// CloneKeepIDs keeps the IDs of the original blocks
+	CloneKeepIDs CloneIDStrategy = iota
+
+	// CloneRegenerateIDs assigns new IDs generated with uid.HumanUid
+	CloneRegenerateIDs
+
+	// CloneRemapIDs assigns the IDs returned by CloneOptions.RemapID,
+	// the IDs are kept if RemapID is nil
+	CloneRemapIDs
+)
+
+// CloneOptions configures Clone
+type CloneOptions struct {
+	// IDStrategy defines how IDs are assigned, defaults to CloneKeepIDs
+	IDStrategy CloneIDStrategy
+
+	// RemapID returns the new ID for an old ID, used with CloneRemapIDs
+	// (if nil, the IDs are kept as with CloneKeepIDs)
+	RemapID func(oldID string) string
+}
+
+// Clone returns a deep copy of the block and its descendants,
+// no children slices or parameter maps are shared with the original
+//
+// The copies are *Block instances, whatever the implementation
+// of the original blocks is.
+//
+// Returns:
+// - BlockInterface - the copy of the block
+// - map[string]string - the new ID of each block, keyed by its old ID,
+// so that references to IDs (i.e. in parameters) can be rewritten
+func Clone(block BlockInterface, options CloneOptions) (BlockInterface, map[string]string) {
+	idMap := map[string]string{}
+
+	if block == nil {
+		return nil, idMap
+	}
+
+	return cloneBlock(block, options, idMap), idMap
+}
+
+func cloneBlock(block BlockInterface, options CloneOptions, idMap map[string]string) BlockInterface {
+	id := cloneID(block.ID(), options)
+	idMap[block.ID()] = id
+
+	parameters := maps.Clone(block.Parameters())
+
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+
+	children := make([]BlockInterface, 0, len(block.Children()))
+
+	for _, child := range block.Children() {
+		if child == nil {
+			continue
+		}
+
+		children = append(children, cloneBlock(child, options, idMap))
+	}
+
+	clone := NewBlock()
+	clone.SetID(id)
+	clone.SetType(block.Type())
+	clone.SetContent(block.Content())
+	clone.SetParameters(parameters)
+	clone.SetChildren(children)
+	return clone
+}
+
+func cloneID(id string, options CloneOptions) string {
+	switch options.IDStrategy {
+	case CloneRegenerateIDs:
+		return uid.HumanUid()
+	case CloneRemapIDs:
+		if options.RemapID == nil {
+			return id
+		}
+
+		return options.RemapID(id)
+	}
+
+	return id
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClone_KeepIDs(t *testing.T) {
+	tree := newTestTree()
+	FindByID(tree, "image1").SetParameter("src", "a.png")
+	FindByID(tree, "paragraph1").SetContent("Hello")
+
+	clone, idMap := Clone(tree, CloneOptions{})
+
+	if got, want := mustToJson(t, clone), mustToJson(t, tree); got != want {
+		t.Errorf("Clone() = %s, want %s", got, want)
+	}
+
+	if len(idMap) != 7 || idMap["image1"] != "image1" {
+		t.Errorf("unexpected ID map %v", idMap)
+	}
+
+	// the clone must not share children or parameters with the original
+	FindByID(clone, "image1").SetParameter("src", "b.png")
+	FindByID(clone, "page1").AddChild(NewBlock())
+
+	if FindByID(tree, "image1").Parameter("src") != "a.png" {
+		t.Error("changing a parameter of the clone changed the original")
+	}
+
+	if len(FindByID(tree, "page1").Children()) != 2 {
+		t.Error("adding a child to the clone changed the original")
+	}
+}
+
+func TestClone_RegenerateIDs(t *testing.T) {
+	tree := newTestTree()
+
+	clone, idMap := Clone(tree, CloneOptions{IDStrategy: CloneRegenerateIDs})
+
+	newIDs := map[string]bool{}
+
+	for oldID, newID := range idMap {
+		if newID == oldID || newID == "" {
+			t.Errorf("ID %q was not regenerated, got %q", oldID, newID)
+		}
+		newIDs[newID] = true
+	}
+
+	if len(newIDs) != 7 {
+		t.Errorf("expected 7 unique new IDs, got %d", len(newIDs))
+	}
+
+	if FindByID(clone, idMap["image2"]).Type() != "image" {
+		t.Error("ID map does not point to the cloned block")
+	}
+}
+
+func TestClone_RemapIDs(t *testing.T) {
+	clone, idMap := Clone(newTestTree(), CloneOptions{
+		IDStrategy: CloneRemapIDs,
+		RemapID: func(oldID string) string {
+			return "copy_" + oldID
+		},
+	})
+
+	got := []string{}
+	for block := range Descendants(clone) {
+		got = append(got, block.ID())
+	}
+
+	want := []string{"copy_page1", "copy_paragraph1", "copy_image1", "copy_page2", "copy_image2", "copy_paragraph2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cloned IDs = %v, want %v", got, want)
+	}
+
+	if clone.ID() != "copy_document1" || idMap["document1"] != "copy_document1" {
+		t.Errorf("unexpected root ID %q, ID map %v", clone.ID(), idMap)
+	}
+}
+
+func TestClone_RemapIDsWithoutRemapID(t *testing.T) {
+	tree := newTestTree()
+	clone, idMap := Clone(tree, CloneOptions{IDStrategy: CloneRemapIDs})
+
+	if got, want := mustToJson(t, clone), mustToJson(t, tree); got != want {
+		t.Errorf("Clone() = %s, want the IDs kept %s", got, want)
+	}
+
+	if idMap["image1"] != "image1" {
+		t.Errorf("ID map = %v, want the IDs kept", idMap)
+	}
+}
+
+func TestClone_Nil(t *testing.T) {
+	clone, idMap := Clone(nil, CloneOptions{})
+
+	if clone != nil || len(idMap) != 0 {
+		t.Errorf("Clone(nil) = %v, %v", clone, idMap)
+	}
+}
@@ -0,0 +1,798 @@
+package ui
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+)
+
+// ErrInvalidOperation is returned when merging a malformed operation
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// OperationKind is the kind of a replicated operation
+type OperationKind string
+
+const (
+	// OperationInsert inserts a block (without children) under a parent
+	OperationInsert OperationKind = "insert"
+
+	// OperationDelete deletes a block, and its descendants
+	OperationDelete OperationKind = "delete"
+
+	// OperationMove moves a block under a new parent
+	OperationMove OperationKind = "move"
+
+	// OperationSetParameter sets a parameter of a block
+	OperationSetParameter OperationKind = "set_parameter"
+
+	// OperationSetContent sets the content of a block
+	OperationSetContent OperationKind = "set_content"
+)
+
+// OperationID identifies an operation, and orders the operations
+// of all replicas: by Counter (a Lamport timestamp), then by Replica
+type OperationID struct {
+	Counter uint64 `json:"counter"`
+	Replica string `json:"replica"`
+}
+
+// Operation is a change of a replicated block tree
+type Operation struct {
+	ID   OperationID   `json:"id"`
+	Kind OperationKind `json:"kind"`
+
+	// BlockID is the ID of the inserted, deleted, moved or changed block
+	BlockID string `json:"block_id"`
+
+	// BlockType and Parameters are the type and parameters
+	// of the inserted block, for OperationInsert
+	BlockType  string            `json:"block_type,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Content is the content of the inserted block for OperationInsert,
+	// or the new content for OperationSetContent
+	Content string `json:"content,omitempty"`
+
+	// ParentID and Position are the parent of the block, and the position
+	// among its siblings, for OperationInsert and OperationMove
+	ParentID string   `json:"parent_id,omitempty"`
+	Position Position `json:"position,omitempty"`
+
+	// Key and Value are the parameter, for OperationSetParameter
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Position orders a block among its siblings. Positions are compared
+// digit by digit, like the digits of a fraction, so there is always
+// a position between two positions, however often a gap is split
+type Position []PositionDigit
+
+// PositionDigit is a digit of a Position. The replica creating the digit
+// breaks ties between digits created concurrently by different replicas
+type PositionDigit struct {
+	Digit   uint64 `json:"digit"`
+	Replica string `json:"replica,omitempty"`
+}
+
+const (
+	// positionBase is the number of digits per level of a position
+	positionBase = 1 << 32
+
+	// positionStep is the maximum distance between the digits of
+	// consecutive positions, leaving room for later inserts
+	positionStep = 1 << 16
+)
+
+// Replica is a copy of a block tree, which can be edited independently
+// of the other replicas, and merged with them in any order. Replicas which
+// have seen the same operations have the same tree (they converge)
+//
+// Each replica keeps the set of all operations. The tree is the result of
+// applying them in the order of their IDs, so all replicas agree on how
+// concurrent edits are resolved:
+// - concurrent parameter or content sets: the last operation wins
+// - concurrent moves: a move which would make a block its own descendant
+// is skipped
+// - edits of deleted blocks (or their descendants) are kept, but not visible
+// - concurrent inserts with the same block ID: the first operation wins
+// - concurrent inserts at the same index: ordered by replica ID
+//
+// The ID of a deleted block can be inserted again (i.e. to undo a delete).
+// The inserted block is a new block: the edits and the children
+// of the deleted block are not carried over
+//
+// All replicas of a tree must be created from the same base tree.
+// Replica is not safe for concurrent use
+//
+// Example:
+//
+//	alice, _ := ui.NewReplica("alice", page)
+//	bob, _ := ui.NewReplica("bob", page)
+//
+//	_ = alice.SetParameter("title1", "text", "Hello")
+//	_ = bob.Move("image1", "section2", 0)
+//
+//	_ = alice.Merge(bob.OperationsSince(alice.Version()))
+//	_ = bob.Merge(alice.OperationsSince(bob.Version()))
+type Replica struct {
+	id        string
+	clock     uint64
+	log       []Operation            // all known operations, in order
+	byReplica map[string][]Operation // the known operations of each replica, in order
+	state     *crdtState
+}
+
+// VersionVector holds the greatest counter of the known operations
+// of each replica (the operations of the base tree have no replica ID)
+type VersionVector map[string]uint64
+
+// NewReplica creates a replica of the base tree, with the replica ID,
+// which must be unique among the replicas
+func NewReplica(id string, base BlockInterface) (*Replica, error) {
+	if id == "" {
+		return nil, errors.New("replica id is required")
+	}
+
+	if base == nil {
+		return nil, errors.New("base block is nil")
+	}
+
+	replica := &Replica{
+		id:        id,
+		byReplica: map[string][]Operation{},
+	}
+
+	for _, operation := range baseOperations(base) {
+		replica.record(operation)
+	}
+
+	replica.rebuild()
+
+	return replica, nil
+}
+
+// ID returns the ID of the replica
+func (r *Replica) ID() string {
+	return r.id
+}
+
+// Block returns a copy of the current tree
+func (r *Replica) Block() BlockInterface {
+	return r.state.toBlock()
+}
+
+// Operations returns all operations known to the replica, in order
+func (r *Replica) Operations() []Operation {
+	return slices.Clone(r.log)
+}
+
+// Version returns the version vector of the operations known to the replica
+func (r *Replica) Version() VersionVector {
+	version := VersionVector{}
+
+	for replica, operations := range r.byReplica {
+		version[replica] = operations[len(operations)-1].ID.Counter
+	}
+
+	return version
+}
+
+// OperationsSince returns the operations not covered by the version
+// (the Version of another replica), in order: the operations of each
+// replica with a counter greater than the counter of the version for it
+//
+// Merging them is enough for the other replica to catch up, as long as
+// the operations it received were merged without gaps (i.e. whole results
+// of Operations or OperationsSince)
+func (r *Replica) OperationsSince(version VersionVector) []Operation {
+	operations := []Operation{}
+
+	for replica, known := range r.byReplica {
+		index, found := slices.BinarySearchFunc(known, version[replica], func(operation Operation, counter uint64) int {
+			return cmp.Compare(operation.ID.Counter, counter)
+		})
+
+		if found {
+			index++
+		}
+
+		operations = append(operations, known[index:]...)
+	}
+
+	slices.SortFunc(operations, compareOperations)
+
+	return operations
+}
+
+// Merge adds the operations of another replica, operations already
+// known are ignored. The operations can be merged in any order, and
+// more than once
+//
+// Operations ordered after all known operations are applied to the current
+// tree directly. The tree is recomputed from all operations only when an
+// operation is ordered before a known one
+//
+// Returns ErrInvalidOperation if an operation is malformed,
+// in which case no operation is merged
+func (r *Replica) Merge(operations []Operation) error {
+	for _, operation := range operations {
+		if err := validateOperation(operation); err != nil {
+			return err
+		}
+	}
+
+	rebuild := false
+
+	for _, operation := range slices.SortedFunc(slices.Values(operations), compareOperations) {
+		last := compareOperations(operation, r.log[len(r.log)-1]) > 0
+
+		if !r.record(cloneOperation(operation)) {
+			continue
+		}
+
+		if !last {
+			rebuild = true
+		}
+
+		if !rebuild {
+			r.state.apply(operation)
+		}
+	}
+
+	if rebuild {
+		r.rebuild()
+	}
+
+	return nil
+}
+
+// Insert inserts the block, and its descendants, at the index
+// of the children of the parent
+//
+// Returns ErrBlockExists if an ID of the blocks is used by a block
+// of the tree. The IDs of deleted blocks can be used again
+func (r *Replica) Insert(parentID string, index int, block BlockInterface) error {
+	if block == nil {
+		return errors.New("block is nil")
+	}
+
+	if !r.state.visible(parentID) {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, parentID)
+	}
+
+	ids := map[string]bool{}
+
+	err := Walk(block, func(b BlockInterface, _ int) error {
+		if r.state.visible(b.ID()) || ids[b.ID()] {
+			return fmt.Errorf("%w: %q", ErrBlockExists, b.ID())
+		}
+		ids[b.ID()] = true
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	siblings := r.state.children(parentID, "")
+
+	if index < 0 || index > len(siblings) {
+		return fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+	}
+
+	r.insert(parentID, positionAt(siblings, index, r.id), block)
+
+	return nil
+}
+
+// Delete deletes the block, and its descendants
+func (r *Replica) Delete(id string) error {
+	if err := r.checkNotRoot(id); err != nil {
+		return err
+	}
+
+	r.apply(Operation{Kind: OperationDelete, BlockID: id})
+
+	return nil
+}
+
+// Move moves the block to the new parent, at the index. The index
+// is the position among the children of the new parent, after the block
+// is removed from its current parent (as for MoveBlock)
+func (r *Replica) Move(id string, newParentID string, index int) error {
+	if err := r.checkNotRoot(id); err != nil {
+		return err
+	}
+
+	if !r.state.visible(newParentID) {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, newParentID)
+	}
+
+	if r.state.isAncestor(id, newParentID) {
+		return fmt.Errorf("%w: %q into %q", ErrCyclicMove, id, newParentID)
+	}
+
+	siblings := r.state.children(newParentID, id)
+
+	if index < 0 || index > len(siblings) {
+		return fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+	}
+
+	r.apply(Operation{
+		Kind:     OperationMove,
+		BlockID:  id,
+		ParentID: newParentID,
+		Position: positionAt(siblings, index, r.id),
+	})
+
+	return nil
+}
+
+// SetParameter sets a parameter of the block
+func (r *Replica) SetParameter(id string, key string, value string) error {
+	if !r.state.visible(id) {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	r.apply(Operation{Kind: OperationSetParameter, BlockID: id, Key: key, Value: value})
+
+	return nil
+}
+
+// SetContent sets the content of the block
+func (r *Replica) SetContent(id string, content string) error {
+	if !r.state.visible(id) {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	r.apply(Operation{Kind: OperationSetContent, BlockID: id, Content: content})
+
+	return nil
+}
+
+func (r *Replica) checkNotRoot(id string) error {
+	if !r.state.visible(id) {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	if id == r.state.rootID {
+		return fmt.Errorf("%w: %q", ErrRootBlock, id)
+	}
+
+	return nil
+}
+
+// insert creates the insert operations of the block and its descendants
+func (r *Replica) insert(parentID string, position Position, block BlockInterface) {
+	r.apply(Operation{
+		Kind:       OperationInsert,
+		BlockID:    block.ID(),
+		BlockType:  block.Type(),
+		Parameters: maps.Clone(block.Parameters()),
+		Content:    block.Content(),
+		ParentID:   parentID,
+		Position:   position,
+	})
+
+	index := 0
+
+	for _, child := range block.Children() {
+		if child == nil {
+			continue
+		}
+
+		r.insert(block.ID(), childPosition(index), child)
+		index++
+	}
+}
+
+// apply records a local operation. It is ordered after all known
+// operations, so it is applied to the current state directly
+func (r *Replica) apply(operation Operation) {
+	operation.ID = OperationID{Counter: r.clock + 1, Replica: r.id}
+	r.record(operation)
+	r.state.apply(operation)
+}
+
+// record adds the operation to the log, and returns false
+// if it is already known
+func (r *Replica) record(operation Operation) bool {
+	known := r.byReplica[operation.ID.Replica]
+	index, found := slices.BinarySearchFunc(known, operation, compareOperations)
+
+	if found {
+		return false
+	}
+
+	r.byReplica[operation.ID.Replica] = slices.Insert(known, index, operation)
+
+	index, _ = slices.BinarySearchFunc(r.log, operation, compareOperations)
+	r.log = slices.Insert(r.log, index, operation)
+	r.clock = max(r.clock, operation.ID.Counter)
+
+	return true
+}
+
+// rebuild recomputes the state from all operations
+func (r *Replica) rebuild() {
+	r.state = newCrdtState()
+
+	for _, operation := range r.log {
+		r.state.apply(operation)
+	}
+}
+
+// baseOperations returns the insert operations of the base tree, which
+// are the same for all replicas created from it
+func baseOperations(base BlockInterface) []Operation {
+	operations := []Operation{}
+
+	var insert func(block BlockInterface, parentID string, position Position)
+
+	insert = func(block BlockInterface, parentID string, position Position) {
+		operations = append(operations, Operation{
+			ID:         OperationID{Counter: uint64(len(operations) + 1)},
+			Kind:       OperationInsert,
+			BlockID:    block.ID(),
+			BlockType:  block.Type(),
+			Parameters: maps.Clone(block.Parameters()),
+			Content:    block.Content(),
+			ParentID:   parentID,
+			Position:   position,
+		})
+
+		index := 0
+
+		for _, child := range block.Children() {
+			if child == nil {
+				continue
+			}
+
+			insert(child, block.ID(), childPosition(index))
+			index++
+		}
+	}
+
+	insert(base, "", nil)
+
+	return operations
+}
+
+func validateOperation(operation Operation) error {
+	if operation.ID.Counter == 0 {
+		return fmt.Errorf("%w: missing counter", ErrInvalidOperation)
+	}
+
+	if operation.BlockID == "" {
+		return fmt.Errorf("%w: missing block id", ErrInvalidOperation)
+	}
+
+	switch operation.Kind {
+	case OperationInsert, OperationDelete, OperationSetContent:
+	case OperationMove:
+		if operation.ParentID == "" {
+			return fmt.Errorf("%w: missing parent id", ErrInvalidOperation)
+		}
+	case OperationSetParameter:
+		if operation.Key == "" {
+			return fmt.Errorf("%w: missing key", ErrInvalidOperation)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidOperation, operation.Kind)
+	}
+
+	return nil
+}
+
+func cloneOperation(operation Operation) Operation {
+	operation.Parameters = maps.Clone(operation.Parameters)
+	operation.Position = slices.Clone(operation.Position)
+	return operation
+}
+
+func compareOperations(a, b Operation) int {
+	return cmp.Or(cmp.Compare(a.ID.Counter, b.ID.Counter), cmp.Compare(a.ID.Replica, b.ID.Replica))
+}
+
+// positionAt returns the position of a block inserted by the replica
+// at the index of the siblings (ordered by position)
+func positionAt(siblings []*crdtNode, index int, replica string) Position {
+	var previous, next Position
+
+	if index > 0 {
+		previous = siblings[index-1].position
+	}
+
+	if index < len(siblings) {
+		next = siblings[index].position
+	}
+
+	return positionBetween(previous, next, replica)
+}
+
+// childPosition returns the position of the child at the index,
+// for the children of an inserted block
+func childPosition(index int) Position {
+	return Position{{Digit: uint64(index+1) * positionStep}}
+}
+
+// positionBetween returns a position after the previous position,
+// and before the next one. A nil previous position is the start,
+// and a nil next position the end
+//
+// At the first level with room between the digits, a new digit is
+// created. Where there is no room, the digit of the previous position
+// is copied, and the new digit is created one level deeper
+func positionBetween(previous Position, next Position, replica string) Position {
+	position := Position{}
+	bounded := next != nil
+
+	for i := 0; ; i++ {
+		low := PositionDigit{}
+
+		if i < len(previous) {
+			low = previous[i]
+		}
+
+		if bounded && i >= len(next) {
+			bounded = false
+		}
+
+		high := uint64(positionBase)
+
+		if bounded {
+			high = next[i].Digit
+		}
+
+		if high > low.Digit && high-low.Digit > 1 {
+			digit := low.Digit + min((high-low.Digit)/2, positionStep)
+			return append(position, PositionDigit{Digit: digit, Replica: replica})
+		}
+
+		level := low
+
+		if i >= len(previous) {
+			// past the end of the previous position, any digit is after it
+			level = PositionDigit{Digit: low.Digit, Replica: replica}
+
+			if bounded && comparePositionDigits(level, next[i]) >= 0 {
+				level = next[i]
+			}
+		}
+
+		position = append(position, level)
+
+		if bounded && comparePositionDigits(level, next[i]) != 0 {
+			bounded = false
+		}
+	}
+}
+
+func comparePositions(a, b Position) int {
+	return slices.CompareFunc(a, b, comparePositionDigits)
+}
+
+func comparePositionDigits(a, b PositionDigit) int {
+	return cmp.Or(cmp.Compare(a.Digit, b.Digit), cmp.Compare(a.Replica, b.Replica))
+}
+
+// == STATE ===================================================================
+
+// crdtState is the tree resulting from applying the operations
+type crdtState struct {
+	rootID string
+	nodes  map[string]*crdtNode
+}
+
+// crdtNode is a block of the state. A block inserted again after a delete
+// is a new incarnation of the node, the children of the previous
+// incarnations are not its children
+type crdtNode struct {
+	id                string
+	incarnation       OperationID // the insert operation of the block
+	blockType         string
+	content           string
+	parentID          string
+	parentIncarnation OperationID
+	position          Position
+	deleted           bool
+	parameters        map[string]string
+}
+
+func newCrdtState() *crdtState {
+	return &crdtState{nodes: map[string]*crdtNode{}}
+}
+
+// apply applies an operation, operations which can not be applied
+// (i.e. their block is not known yet) are skipped
+func (s *crdtState) apply(operation Operation) {
+	node := s.nodes[operation.BlockID]
+
+	switch operation.Kind {
+	case OperationInsert:
+		// a block which is not visible (deleted) is inserted again
+		if node != nil && (s.visible(node.id) || s.isAncestor(node.id, operation.ParentID)) {
+			return
+		}
+
+		if operation.ParentID == "" {
+			if s.rootID != "" {
+				return
+			}
+			s.rootID = operation.BlockID
+		}
+
+		parameters := maps.Clone(operation.Parameters)
+
+		if parameters == nil {
+			parameters = map[string]string{}
+		}
+
+		node = &crdtNode{
+			id:          operation.BlockID,
+			incarnation: operation.ID,
+			blockType:   operation.BlockType,
+			content:     operation.Content,
+			parentID:    operation.ParentID,
+			position:    operation.Position,
+			parameters:  parameters,
+		}
+
+		if parent := s.nodes[operation.ParentID]; parent != nil {
+			node.parentIncarnation = parent.incarnation
+		}
+
+		s.nodes[operation.BlockID] = node
+	case OperationDelete:
+		if node != nil && node.id != s.rootID {
+			node.deleted = true
+		}
+	case OperationMove:
+		parent := s.nodes[operation.ParentID]
+
+		if node == nil || node.id == s.rootID || parent == nil {
+			return
+		}
+
+		if s.isAncestor(node.id, operation.ParentID) {
+			return
+		}
+
+		node.parentID = operation.ParentID
+		node.parentIncarnation = parent.incarnation
+		node.position = operation.Position
+	case OperationSetParameter:
+		if node != nil {
+			node.parameters[operation.Key] = operation.Value
+		}
+	case OperationSetContent:
+		if node != nil {
+			node.content = operation.Content
+		}
+	}
+}
+
+// isAncestor returns true if the block with the ID is the block
+// with the descendant ID, or one of its ancestors
+func (s *crdtState) isAncestor(id string, descendantID string) bool {
+	for current := range s.ancestors(descendantID) {
+		if current.id == id {
+			return true
+		}
+
+		if current.id == s.rootID {
+			return false
+		}
+	}
+
+	return false
+}
+
+// visible returns true if the block, and all its ancestors,
+// are in the tree and not deleted
+func (s *crdtState) visible(id string) bool {
+	for current := range s.ancestors(id) {
+		if current.deleted {
+			return false
+		}
+
+		if current.id == s.rootID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ancestors yields the block with the ID, and its ancestors. It stops
+// after as many steps as there are blocks, so that parent cycles created
+// by malformed inserts can not loop forever
+func (s *crdtState) ancestors(id string) iter.Seq[*crdtNode] {
+	return func(yield func(*crdtNode) bool) {
+		current := s.nodes[id]
+
+		for steps := 0; current != nil && steps <= len(s.nodes); steps++ {
+			if !yield(current) {
+				return
+			}
+			current = s.parent(current)
+		}
+	}
+}
+
+// parent returns the parent of the node, or nil if the node has no parent,
+// or if its parent was inserted again since (the node is not its child)
+func (s *crdtState) parent(node *crdtNode) *crdtNode {
+	parent := s.nodes[node.parentID]
+
+	if parent == nil || parent.incarnation != node.parentIncarnation {
+		return nil
+	}
+
+	return parent
+}
+
+// children returns the children of the parent, which are not deleted,
+// in order, without the block with the excluded ID
+func (s *crdtState) children(parentID string, excludedID string) []*crdtNode {
+	children := []*crdtNode{}
+	parent := s.nodes[parentID]
+
+	for _, node := range s.nodes {
+		if node.parentID == parentID && s.parent(node) == parent && !node.deleted && node.id != excludedID && node.id != s.rootID {
+			children = append(children, node)
+		}
+	}
+
+	slices.SortFunc(children, compareNodes)
+
+	return children
+}
+
+func compareNodes(a, b *crdtNode) int {
+	return cmp.Or(comparePositions(a.position, b.position), cmp.Compare(a.id, b.id))
+}
+
+// toBlock returns the tree of the blocks, which are not deleted
+func (s *crdtState) toBlock() BlockInterface {
+	root := s.nodes[s.rootID]
+
+	if root == nil {
+		return nil
+	}
+
+	childrenOf := map[string][]*crdtNode{}
+
+	for _, node := range s.nodes {
+		if !node.deleted && node.id != s.rootID && s.parent(node) != nil {
+			childrenOf[node.parentID] = append(childrenOf[node.parentID], node)
+		}
+	}
+
+	var build func(node *crdtNode) BlockInterface
+
+	build = func(node *crdtNode) BlockInterface {
+		nodes := childrenOf[node.id]
+		slices.SortFunc(nodes, compareNodes)
+
+		children := make([]BlockInterface, 0, len(nodes))
+
+		for _, child := range nodes {
+			children = append(children, build(child))
+		}
+
+		block := NewBlock()
+		block.SetID(node.id)
+		block.SetType(node.blockType)
+		block.SetContent(node.content)
+		block.SetParameters(maps.Clone(node.parameters))
+		block.SetChildren(children)
+		return block
+	}
+
+	return build(root)
+}
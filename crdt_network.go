@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Network simulates replicas of a block tree exchanging operations
+// over an unreliable network, in memory. It is meant for tests: messages
+// are delivered in a random (but reproducible, for a seed) order
+//
+// Example:
+//
+//	network, _ := ui.NewNetwork(page, 1, "alice", "bob")
+//	_ = network.Replica("alice").SetParameter("title1", "text", "Hello")
+//	_ = network.Replica("bob").Delete("title1")
+//	_ = network.Sync()
+//	converged, _ := network.Converged()
+type Network struct {
+	replicas []*Replica
+	messages []networkMessage
+	random   *rand.Rand
+}
+
+type networkMessage struct {
+	to         *Replica
+	operations []Operation
+}
+
+// NewNetwork creates a replica of the base tree for each replica ID.
+// The seed makes the delivery order reproducible
+func NewNetwork(base BlockInterface, seed uint64, replicaIDs ...string) (*Network, error) {
+	network := &Network{
+		random: rand.New(rand.NewPCG(seed, seed)),
+	}
+
+	for _, id := range replicaIDs {
+		if network.Replica(id) != nil {
+			return nil, fmt.Errorf("duplicate replica id %q", id)
+		}
+
+		replica, err := NewReplica(id, base)
+
+		if err != nil {
+			return nil, err
+		}
+
+		network.replicas = append(network.replicas, replica)
+	}
+
+	return network, nil
+}
+
+// Replica returns the replica with the ID, or nil if not found
+func (n *Network) Replica(id string) *Replica {
+	for _, replica := range n.replicas {
+		if replica.ID() == id {
+			return replica
+		}
+	}
+
+	return nil
+}
+
+// Replicas returns all replicas
+func (n *Network) Replicas() []*Replica {
+	return slices.Clone(n.replicas)
+}
+
+// Broadcast sends the operations known to the replica to all other
+// replicas. The messages are in flight until delivered
+func (n *Network) Broadcast(id string) {
+	from := n.Replica(id)
+
+	if from == nil {
+		return
+	}
+
+	operations := from.Operations()
+
+	for _, to := range n.replicas {
+		if to != from {
+			n.messages = append(n.messages, networkMessage{to: to, operations: operations})
+		}
+	}
+}
+
+// Pending returns the number of messages in flight
+func (n *Network) Pending() int {
+	return len(n.messages)
+}
+
+// DeliverOne delivers a random message in flight. Only a random part
+// of its operations may be delivered, the rest stays in flight
+//
+// Returns false if there is no message in flight
+func (n *Network) DeliverOne() (bool, error) {
+	if len(n.messages) == 0 {
+		return false, nil
+	}
+
+	index := n.random.IntN(len(n.messages))
+	message := n.messages[index]
+	n.messages = slices.Delete(n.messages, index, index+1)
+
+	operations := slices.Clone(message.operations)
+	n.random.Shuffle(len(operations), func(i, j int) {
+		operations[i], operations[j] = operations[j], operations[i]
+	})
+
+	split := n.random.IntN(len(operations) + 1)
+
+	if split < len(operations) {
+		n.messages = append(n.messages, networkMessage{to: message.to, operations: operations[split:]})
+	}
+
+	return true, message.to.Merge(operations[:split])
+}
+
+// Deliver delivers all messages in flight, in a random order
+func (n *Network) Deliver() error {
+	for {
+		delivered, err := n.DeliverOne()
+
+		if err != nil || !delivered {
+			return err
+		}
+	}
+}
+
+// Sync broadcasts the operations of all replicas, and delivers all
+// messages, after which all replicas have seen the same operations
+func (n *Network) Sync() error {
+	for _, replica := range n.replicas {
+		n.Broadcast(replica.ID())
+	}
+
+	return n.Deliver()
+}
+
+// Converged returns true if all replicas have the same tree
+func (n *Network) Converged() (bool, error) {
+	var first string
+
+	for i, replica := range n.replicas {
+		blockJson, err := replica.Block().ToJson()
+
+		if err != nil {
+			return false, err
+		}
+
+		if i == 0 {
+			first = blockJson
+		} else if blockJson != first {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
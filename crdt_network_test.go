@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestNetwork(t *testing.T) {
+	network, err := NewNetwork(newTestTree(), 1, "alice", "bob")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = network.Replica("alice").SetParameter("paragraph1", "text", "Hello")
+	_ = network.Replica("bob").Delete("image1")
+
+	network.Broadcast("alice")
+
+	if network.Pending() != 1 {
+		t.Errorf("Pending() = %d, want 1", network.Pending())
+	}
+
+	if err := network.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if converged, err := network.Converged(); err != nil || !converged {
+		t.Fatalf("expected the replicas to converge, %v", err)
+	}
+
+	block := network.Replica("alice").Block()
+
+	if FindByID(block, "paragraph1").Parameter("text") != "Hello" || FindByID(block, "image1") != nil {
+		t.Errorf("unexpected tree %s", mustToJson(t, block))
+	}
+
+	if _, err := NewNetwork(newTestTree(), 1, "alice", "alice"); err == nil {
+		t.Error("expected an error for duplicate replica ids")
+	}
+}
+
+func TestNetwork_RandomEdits(t *testing.T) {
+	for seed := uint64(1); seed <= 30; seed++ {
+		network, err := NewNetwork(newTestTree(), seed, "alice", "bob", "carol")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		random := rand.New(rand.NewPCG(seed, 0))
+
+		for step := 0; step < 60; step++ {
+			replicas := network.Replicas()
+			replica := replicas[random.IntN(len(replicas))]
+
+			randomEdit(random, replica, strconv.Itoa(step))
+
+			switch random.IntN(4) {
+			case 0:
+				network.Broadcast(replica.ID())
+			case 1:
+				if _, err := network.DeliverOne(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		if err := network.Sync(); err != nil {
+			t.Fatal(err)
+		}
+
+		converged, err := network.Converged()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !converged {
+			t.Fatalf("seed %d: replicas did not converge", seed)
+		}
+	}
+}
+
+// randomEdit makes a random edit of a random block of the replica,
+// invalid edits (i.e. cyclic moves) are rejected by the replica
+func randomEdit(random *rand.Rand, replica *Replica, suffix string) {
+	root := replica.Block()
+	ids := blockIDs(append([]BlockInterface{root}, slices.Collect(Descendants(root))...))
+	id := ids[random.IntN(len(ids))]
+	otherID := ids[random.IntN(len(ids))]
+
+	switch random.IntN(4) {
+	case 0:
+		block := NewBlockBuilder().WithID(replica.ID() + suffix).WithType("paragraph").Build()
+		_ = replica.Insert(id, random.IntN(3), block)
+	case 1:
+		_ = replica.Delete(id)
+	case 2:
+		_ = replica.Move(id, otherID, random.IntN(3))
+	case 3:
+		_ = replica.SetParameter(id, "text", replica.ID()+suffix)
+	}
+}
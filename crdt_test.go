@@ -0,0 +1,412 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func newTestReplicas(t *testing.T) (*Replica, *Replica) {
+	t.Helper()
+
+	alice, err := NewReplica("alice", newTestTree())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bob, err := NewReplica("bob", newTestTree())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return alice, bob
+}
+
+func mergeReplicas(t *testing.T, replicas ...*Replica) {
+	t.Helper()
+
+	for _, to := range replicas {
+		for _, from := range replicas {
+			if err := to.Merge(from.Operations()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	want := mustToJson(t, replicas[0].Block())
+
+	for _, replica := range replicas[1:] {
+		if got := mustToJson(t, replica.Block()); got != want {
+			t.Fatalf("replicas did not converge:\n%s\n%s", want, got)
+		}
+	}
+}
+
+func TestNewReplica(t *testing.T) {
+	tree := newTestTree()
+	tree.SetParameter("title", "Home")
+
+	replica, err := NewReplica("alice", tree)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := mustToJson(t, replica.Block()), mustToJson(t, tree); got != want {
+		t.Errorf("Block() = %s, want %s", got, want)
+	}
+
+	if _, err := NewReplica("", tree); err == nil {
+		t.Error("expected an error for an empty replica id")
+	}
+
+	if _, err := NewReplica("alice", nil); err == nil {
+		t.Error("expected an error for a nil base")
+	}
+}
+
+func TestReplica_Edits(t *testing.T) {
+	replica, _ := newTestReplicas(t)
+
+	section := NewBlockBuilder().WithID("section1").WithType("section").
+		WithChildren([]BlockInterface{NewBlockBuilder().WithID("heading1").WithType("heading").Build()}).
+		Build()
+
+	if err := replica.Insert("page1", 1, section); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := replica.Move("paragraph2", "section1", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := replica.SetParameter("heading1", "level", "2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := replica.Delete("image2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	block := replica.Block()
+
+	if got := childIDs(FindByID(block, "page1")); !reflect.DeepEqual(got, []string{"paragraph1", "section1", "image1"}) {
+		t.Errorf("children of page1 = %v", got)
+	}
+
+	if got := childIDs(FindByID(block, "section1")); !reflect.DeepEqual(got, []string{"heading1", "paragraph2"}) {
+		t.Errorf("children of section1 = %v", got)
+	}
+
+	if FindByID(block, "heading1").Parameter("level") != "2" || FindByID(block, "image2") != nil {
+		t.Errorf("unexpected tree %s", mustToJson(t, block))
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{"insert existing", replica.Insert("page2", 0, NewBlockBuilder().WithID("paragraph1").Build()), ErrBlockExists},
+		{"insert into missing", replica.Insert("missing", 0, NewBlock()), ErrBlockNotFound},
+		{"insert out of range", replica.Insert("page2", 5, NewBlock()), ErrIndexOutOfRange},
+		{"move into descendant", replica.Move("page1", "section1", 0), ErrCyclicMove},
+		{"move root", replica.Move("document1", "page1", 0), ErrRootBlock},
+		{"delete deleted", replica.Delete("image2"), ErrBlockNotFound},
+		{"set deleted", replica.SetParameter("image2", "src", "a.png"), ErrBlockNotFound},
+	}
+
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.wantErr, tt.err)
+		}
+	}
+}
+
+func TestReplica_ConcurrentParameterSet(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.SetParameter("image1", "src", "alice.png")
+	_ = bob.SetParameter("image1", "src", "bob.png")
+
+	mergeReplicas(t, alice, bob)
+
+	if got := FindByID(alice.Block(), "image1").Parameter("src"); got != "bob.png" {
+		t.Errorf("src = %q, want the operation ordered last to win", got)
+	}
+}
+
+func TestReplica_Content(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.SetContent("paragraph1", "Alice")
+	_ = bob.SetContent("paragraph1", "Bob")
+	_ = bob.Insert("page2", 0, NewBlockBuilder().WithID("heading1").WithType("heading").WithContent("Title").Build())
+
+	mergeReplicas(t, alice, bob)
+
+	block := alice.Block()
+
+	if FindByID(block, "paragraph1").Content() != "Bob" || FindByID(block, "heading1").Content() != "Title" {
+		t.Errorf("unexpected tree %s", mustToJson(t, block))
+	}
+}
+
+func TestReplica_ConcurrentCyclicMoves(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	if err := alice.Move("page1", "page2", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bob.Move("page2", "page1", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	mergeReplicas(t, alice, bob)
+
+	block := alice.Block()
+
+	if got := childIDs(block); !reflect.DeepEqual(got, []string{"page2"}) {
+		t.Errorf("children of document1 = %v", got)
+	}
+
+	if FindParent(block, "page1").ID() != "page2" {
+		t.Errorf("the second move must be skipped: %s", mustToJson(t, block))
+	}
+}
+
+func TestReplica_ConcurrentDeleteAndEdit(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.Delete("page1")
+	_ = bob.SetParameter("paragraph1", "text", "Hello")
+	_ = bob.Insert("page1", 0, NewBlockBuilder().WithID("heading1").WithType("heading").Build())
+	_ = bob.Move("image2", "page1", 0)
+
+	mergeReplicas(t, alice, bob)
+
+	block := alice.Block()
+
+	if FindByID(block, "page1") != nil || FindByID(block, "heading1") != nil || FindByID(block, "image2") != nil {
+		t.Errorf("the deleted subtree must not be visible: %s", mustToJson(t, block))
+	}
+}
+
+func TestReplica_ConcurrentInserts(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.Insert("page2", 1, NewBlockBuilder().WithID("a").WithType("heading").Build())
+	_ = bob.Insert("page2", 1, NewBlockBuilder().WithID("b").WithType("heading").Build())
+	_ = bob.Insert("page2", 2, NewBlockBuilder().WithID("c").WithType("heading").Build())
+
+	mergeReplicas(t, alice, bob)
+
+	if got := childIDs(FindByID(alice.Block(), "page2")); !reflect.DeepEqual(got, []string{"image2", "a", "b", "c", "paragraph2"}) {
+		t.Errorf("children of page2 = %v", got)
+	}
+}
+
+func TestReplica_RepeatedInsertsInOneGap(t *testing.T) {
+	replica, err := NewReplica("alice", NewBlockBuilder().WithID("page1").WithType("page").
+		WithChildren([]BlockInterface{
+			NewBlockBuilder().WithID("a").WithType("paragraph").Build(),
+			NewBlockBuilder().WithID("z").WithType("paragraph").Build(),
+		}).
+		Build())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a"}
+
+	// always just before z, far past the precision of a float64 midpoint
+	for i := range 200 {
+		id := fmt.Sprintf("n%03d", 999-i)
+
+		if err := replica.Insert("page1", i+1, NewBlockBuilder().WithID(id).WithType("paragraph").Build()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want = append(want, id)
+	}
+
+	// and always just after a
+	for i := range 200 {
+		id := fmt.Sprintf("m%03d", i)
+
+		if err := replica.Insert("page1", 1, NewBlockBuilder().WithID(id).WithType("paragraph").Build()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want = slices.Insert(want, 1, id)
+	}
+
+	want = append(want, "z")
+
+	if got := childIDs(replica.Block()); !reflect.DeepEqual(got, want) {
+		t.Errorf("children of page1 = %v, want %v", got, want)
+	}
+}
+
+func TestReplica_ConcurrentInsertsThenInsertBetween(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.Insert("page2", 1, NewBlockBuilder().WithID("b").WithType("heading").Build())
+	_ = bob.Insert("page2", 1, NewBlockBuilder().WithID("a").WithType("heading").Build())
+
+	mergeReplicas(t, alice, bob)
+
+	// the concurrent inserts split the same gap, ordered by replica ID
+	if got := childIDs(FindByID(alice.Block(), "page2")); !reflect.DeepEqual(got, []string{"image2", "b", "a", "paragraph2"}) {
+		t.Fatalf("children of page2 = %v", got)
+	}
+
+	for i := range 20 {
+		id := fmt.Sprintf("c%02d", i)
+
+		if err := bob.Insert("page2", 2+i, NewBlockBuilder().WithID(id).WithType("heading").Build()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	mergeReplicas(t, alice, bob)
+
+	got := childIDs(FindByID(alice.Block(), "page2"))
+
+	if got[1] != "b" || got[2] != "c00" || got[21] != "c19" || got[22] != "a" {
+		t.Errorf("children of page2 = %v", got)
+	}
+}
+
+func TestReplica_Merge(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.SetParameter("image1", "src", "a.png")
+
+	operations := alice.Operations()
+
+	if err := bob.Merge(operations); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := bob.Merge(operations); err != nil {
+		t.Fatalf("unexpected error merging twice: %v", err)
+	}
+
+	if len(bob.Operations()) != len(operations) {
+		t.Errorf("operations = %d, want %d", len(bob.Operations()), len(operations))
+	}
+
+	_ = bob.SetParameter("image1", "src", "b.png")
+
+	mergeReplicas(t, alice, bob)
+
+	if got := FindByID(alice.Block(), "image1").Parameter("src"); got != "b.png" {
+		t.Errorf("src = %q, an operation made after a merge must win", got)
+	}
+
+	invalid := []Operation{{ID: OperationID{Counter: 100, Replica: "carol"}, Kind: "rename", BlockID: "image1"}}
+
+	if err := bob.Merge(invalid); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("expected ErrInvalidOperation, got %v", err)
+	}
+
+	cyclic := []Operation{
+		{ID: OperationID{Counter: 100, Replica: "carol"}, Kind: OperationInsert, BlockID: "x", ParentID: "y"},
+		{ID: OperationID{Counter: 101, Replica: "carol"}, Kind: OperationInsert, BlockID: "y", ParentID: "x"},
+		{ID: OperationID{Counter: 102, Replica: "carol"}, Kind: OperationMove, BlockID: "image1", ParentID: "x"},
+	}
+
+	if err := bob.Merge(cyclic); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if FindByID(bob.Block(), "image1") != nil || bob.SetParameter("x", "a", "b") == nil {
+		t.Error("blocks not connected to the root must not be visible")
+	}
+}
+
+func TestReplica_InsertAfterDelete(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = bob.SetParameter("page1", "title", "Bob")
+
+	if err := alice.Delete("page1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	page := NewBlockBuilder().WithID("page1").WithType("page").Build()
+
+	if err := alice.Insert("document1", 0, page); err != nil {
+		t.Fatalf("unexpected error inserting a deleted block again: %v", err)
+	}
+
+	if err := alice.Insert("page2", 0, NewBlockBuilder().WithID("image1").WithType("image").Build()); err != nil {
+		t.Fatalf("unexpected error inserting a descendant of a deleted block: %v", err)
+	}
+
+	mergeReplicas(t, alice, bob)
+
+	block := bob.Block()
+
+	if got := childIDs(FindByID(block, "page1")); len(got) != 0 {
+		t.Errorf("children of page1 = %v, the children of the deleted block must not come back", got)
+	}
+
+	if FindByID(block, "page1").HasParameter("title") {
+		t.Error("the edits of the deleted block must not be carried over")
+	}
+
+	if got := childIDs(FindByID(block, "page2")); !reflect.DeepEqual(got, []string{"image1", "image2", "paragraph2"}) {
+		t.Errorf("children of page2 = %v", got)
+	}
+
+	if err := bob.Insert("page2", 0, page); !errors.Is(err, ErrBlockExists) {
+		t.Errorf("expected ErrBlockExists, got %v", err)
+	}
+}
+
+func TestReplica_OperationsSince(t *testing.T) {
+	alice, bob := newTestReplicas(t)
+
+	_ = alice.SetParameter("image1", "src", "a.png")
+	_ = bob.SetContent("paragraph1", "Hello")
+
+	toBob := alice.OperationsSince(bob.Version())
+
+	if len(toBob) != 1 || toBob[0].Kind != OperationSetParameter {
+		t.Fatalf("OperationsSince() = %v", toBob)
+	}
+
+	if err := bob.Merge(toBob); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := alice.Merge(bob.OperationsSince(alice.Version())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := mustToJson(t, alice.Block()), mustToJson(t, bob.Block()); got != want {
+		t.Fatalf("replicas did not converge:\n%s\n%s", got, want)
+	}
+
+	want := VersionVector{"": 7, "alice": 8, "bob": 8}
+
+	if got := alice.Version(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Version() = %v, want %v", got, want)
+	}
+
+	if got := alice.OperationsSince(bob.Version()); len(got) != 0 {
+		t.Errorf("OperationsSince() = %v, want no operations", got)
+	}
+
+	if got := alice.OperationsSince(nil); !reflect.DeepEqual(got, alice.Operations()) {
+		t.Errorf("OperationsSince(nil) = %v, want all operations", got)
+	}
+}
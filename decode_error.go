@@ -0,0 +1,26 @@
+package ui
+
+import "errors"
+
+// ErrMissingField is returned when decoding a block without a required field
+var ErrMissingField = errors.New("missing field")
+
+// ErrInvalidFieldType is returned when decoding a block field of the wrong type
+var ErrInvalidFieldType = errors.New("invalid field type")
+
+// DecodeError is returned when a block cannot be decoded from JSON or a map
+type DecodeError struct {
+	// Path is the JSON path of the offending field, i.e. $.children[0].parameters.width
+	Path string
+
+	// Err is the reason, i.e. ErrMissingField or ErrInvalidFieldType
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
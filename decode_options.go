@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDecodeLimitExceeded is returned when decoding blocks exceeds
+// one of the limits of DecodeOptions
+var ErrDecodeLimitExceeded = errors.New("decode limit exceeded")
+
+// DecodeOptions limits the resources used when decoding blocks from JSON,
+// to protect against hostile input. A zero value means no limit
+type DecodeOptions struct {
+	// MaxDepth is the maximum nesting depth of blocks, the root block is at depth 1
+	MaxDepth int
+
+	// MaxBlocks is the maximum total number of blocks
+	MaxBlocks int
+
+	// MaxParameters is the maximum number of parameters per block
+	MaxParameters int
+
+	// MaxParameterValueSize is the maximum size of a parameter value in bytes
+	MaxParameterValueSize int
+
+	// MaxContentSize is the maximum size of the content of a block in bytes
+	MaxContentSize int
+
+	// MaxInputBytes is the maximum size of the JSON input in bytes
+	MaxInputBytes int
+}
+
+// DefaultDecodeOptions returns limits suitable for decoding
+// blocks received from untrusted clients
+func DefaultDecodeOptions() DecodeOptions {
+	return DecodeOptions{
+		MaxDepth:              64,
+		MaxBlocks:             10_000,
+		MaxParameters:         256,
+		MaxParameterValueSize: 64 * 1024,
+		MaxContentSize:        1024 * 1024,
+		MaxInputBytes:         10 * 1024 * 1024,
+	}
+}
+
+// DecodeLimitError is returned when decoding blocks exceeds a limit,
+// it matches ErrDecodeLimitExceeded with errors.Is
+type DecodeLimitError struct {
+	// Limit is the name of the exceeded DecodeOptions field, i.e. MaxDepth
+	Limit string
+
+	// Max is the configured value of the limit
+	Max int
+
+	// Path is the JSON path where the limit was exceeded
+	Path string
+}
+
+func (e *DecodeLimitError) Error() string {
+	return fmt.Sprintf("%s: %s: %s is %d", e.Path, ErrDecodeLimitExceeded.Error(), e.Limit, e.Max)
+}
+
+func (e *DecodeLimitError) Is(target error) bool {
+	return target == ErrDecodeLimitExceeded
+}
+
+// NewBlockFromJsonWithOptions creates a block from its JSON representation,
+// like NewBlockFromJson, enforcing the limits of the options
+func NewBlockFromJsonWithOptions(blockJson string, options DecodeOptions) (BlockInterface, error) {
+	state := &decodeState{options: options}
+
+	if err := state.checkInput([]byte(blockJson), 0); err != nil {
+		return nil, err
+	}
+
+	block, err := decodeBlockJson([]byte(blockJson), "$", 1, state)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// UnmarshalJsonToBlocksWithOptions unmarshals a JSON array of blocks,
+// like UnmarshalJsonToBlocks, enforcing the limits of the options
+func UnmarshalJsonToBlocksWithOptions(blocksJson string, options DecodeOptions) ([]BlockInterface, error) {
+	state := &decodeState{options: options}
+
+	if err := state.checkInput([]byte(blocksJson), 1); err != nil {
+		return nil, err
+	}
+
+	return unmarshalJsonToBlocks([]byte(blocksJson), "$", state)
+}
+
+// decodeState tracks the resources used while decoding
+type decodeState struct {
+	options DecodeOptions
+	blocks  int
+}
+
+// checkInput checks the input size, and scans the nesting of the raw JSON,
+// so that deeply nested input is rejected before it is decoded
+//
+// Each block adds two levels of JSON nesting (the block object and
+// its children array), and its parameters object adds one more.
+// The wrapperLevels are the levels of nesting around the root block(s)
+func (s *decodeState) checkInput(data []byte, wrapperLevels int) error {
+	if s.options.MaxInputBytes > 0 && len(data) > s.options.MaxInputBytes {
+		return &DecodeLimitError{Limit: "MaxInputBytes", Max: s.options.MaxInputBytes, Path: "$"}
+	}
+
+	if s.options.MaxDepth > 0 && jsonNestingDepth(data) > wrapperLevels+2*s.options.MaxDepth {
+		return &DecodeLimitError{Limit: "MaxDepth", Max: s.options.MaxDepth, Path: "$"}
+	}
+
+	return nil
+}
+
+// enterBlock checks the depth and the number of blocks,
+// when the decoding of a block starts
+func (s *decodeState) enterBlock(path string, depth int) error {
+	if s.options.MaxDepth > 0 && depth > s.options.MaxDepth {
+		return &DecodeLimitError{Limit: "MaxDepth", Max: s.options.MaxDepth, Path: path}
+	}
+
+	s.blocks++
+
+	if s.options.MaxBlocks > 0 && s.blocks > s.options.MaxBlocks {
+		return &DecodeLimitError{Limit: "MaxBlocks", Max: s.options.MaxBlocks, Path: path}
+	}
+
+	return nil
+}
+
+// checkFields checks the limits for the fields of a decoded block
+func (s *decodeState) checkFields(path string, content string, parameters map[string]string) error {
+	if s.options.MaxContentSize > 0 && len(content) > s.options.MaxContentSize {
+		return &DecodeLimitError{Limit: "MaxContentSize", Max: s.options.MaxContentSize, Path: path + ".content"}
+	}
+
+	if s.options.MaxParameters > 0 && len(parameters) > s.options.MaxParameters {
+		return &DecodeLimitError{Limit: "MaxParameters", Max: s.options.MaxParameters, Path: path + ".parameters"}
+	}
+
+	if s.options.MaxParameterValueSize > 0 {
+		for key, value := range parameters {
+			if len(value) > s.options.MaxParameterValueSize {
+				return &DecodeLimitError{Limit: "MaxParameterValueSize", Max: s.options.MaxParameterValueSize, Path: path + ".parameters." + key}
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonNestingDepth returns the maximum nesting of objects and arrays
+// in the JSON, without decoding it
+func jsonNestingDepth(data []byte) int {
+	depth := 0
+	maxDepth := 0
+	inString := false
+	escaped := false
+
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+
+	return maxDepth
+}
@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// nestedBlockJson returns the JSON of a chain of blocks, depth blocks deep
+func nestedBlockJson(depth int) string {
+	return strings.Repeat(`{"id":"x","type":"a","children":[`, depth-1) +
+		`{"id":"x","type":"a","parameters":{},"children":[]}` +
+		strings.Repeat(`]}`, depth-1)
+}
+
+func TestNewBlockFromJsonWithOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		options   DecodeOptions
+		wantLimit string
+		wantPath  string
+	}{
+		{
+			name:    "no limits",
+			json:    nestedBlockJson(100),
+			options: DecodeOptions{},
+		},
+		{
+			name:    "within limits",
+			json:    nestedBlockJson(3),
+			options: DecodeOptions{MaxDepth: 3, MaxBlocks: 3, MaxInputBytes: 1000},
+		},
+		{
+			name:      "max depth",
+			json:      nestedBlockJson(4),
+			options:   DecodeOptions{MaxDepth: 3},
+			wantLimit: "MaxDepth",
+			wantPath:  "$",
+		},
+		{
+			name:      "max depth deeply nested input",
+			json:      strings.Repeat("[", 100000),
+			options:   DecodeOptions{MaxDepth: 3},
+			wantLimit: "MaxDepth",
+			wantPath:  "$",
+		},
+		{
+			name:      "max blocks",
+			json:      `{"id":"1","type":"a","children":[{"id":"2","type":"b"},{"id":"3","type":"b"}]}`,
+			options:   DecodeOptions{MaxBlocks: 2},
+			wantLimit: "MaxBlocks",
+			wantPath:  "$.children[1]",
+		},
+		{
+			name:      "max parameters",
+			json:      `{"id":"1","type":"a","children":[{"id":"2","type":"b","parameters":{"a":"1","b":"2"}}]}`,
+			options:   DecodeOptions{MaxParameters: 1},
+			wantLimit: "MaxParameters",
+			wantPath:  "$.children[0].parameters",
+		},
+		{
+			name:      "max parameter value size",
+			json:      `{"id":"1","type":"a","parameters":{"a":"12345"}}`,
+			options:   DecodeOptions{MaxParameterValueSize: 4},
+			wantLimit: "MaxParameterValueSize",
+			wantPath:  "$.parameters.a",
+		},
+		{
+			name:      "max content size",
+			json:      `{"id":"1","type":"a","content":"12345"}`,
+			options:   DecodeOptions{MaxContentSize: 4},
+			wantLimit: "MaxContentSize",
+			wantPath:  "$.content",
+		},
+		{
+			name:      "max input bytes",
+			json:      nestedBlockJson(1),
+			options:   DecodeOptions{MaxInputBytes: 10},
+			wantLimit: "MaxInputBytes",
+			wantPath:  "$",
+		},
+		{
+			name:    "brackets in strings are not nesting",
+			json:    `{"id":"[[[[[[","type":"{{{{{{","parameters":{"a":"\"[[[[["}}`,
+			options: DecodeOptions{MaxDepth: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBlockFromJsonWithOptions(tt.json, tt.options)
+
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrDecodeLimitExceeded) {
+				t.Fatalf("expected ErrDecodeLimitExceeded, got %v", err)
+			}
+
+			var limitErr *DecodeLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected *DecodeLimitError, got %T", err)
+			}
+
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Limit = %q, want %q", limitErr.Limit, tt.wantLimit)
+			}
+
+			if limitErr.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", limitErr.Path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJsonToBlocksWithOptions(t *testing.T) {
+	blocksJson := "[" + nestedBlockJson(2) + "," + nestedBlockJson(2) + "]"
+
+	blocks, err := UnmarshalJsonToBlocksWithOptions(blocksJson, DecodeOptions{MaxDepth: 2, MaxBlocks: 4})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+
+	_, err = UnmarshalJsonToBlocksWithOptions(blocksJson, DecodeOptions{MaxBlocks: 3})
+
+	var limitErr *DecodeLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxBlocks" || limitErr.Path != "$[1].children[0]" {
+		t.Errorf("expected MaxBlocks at $[1].children[0], got %v", err)
+	}
+
+	_, err = UnmarshalJsonToBlocksWithOptions(blocksJson, DecodeOptions{MaxDepth: 1})
+
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxDepth" {
+		t.Errorf("expected MaxDepth, got %v", err)
+	}
+}
+
+func TestDefaultDecodeOptions(t *testing.T) {
+	if _, err := NewBlockFromJsonWithOptions(nestedBlockJson(10), DefaultDecodeOptions()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := NewBlockFromJsonWithOptions(nestedBlockJson(1000), DefaultDecodeOptions()); !errors.Is(err, ErrDecodeLimitExceeded) {
+		t.Errorf("expected ErrDecodeLimitExceeded, got %v", err)
+	}
+}
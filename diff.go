@@ -0,0 +1,332 @@
+package ui
+
+import (
+	"slices"
+)
+
+// ChangeKind is the kind of a change reported by Diff
+type ChangeKind string
+
+const (
+	// ChangeAdded is a block present only in the new tree
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeRemoved is a block present only in the old tree
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeMoved is a block moved to another parent
+	ChangeMoved ChangeKind = "moved"
+
+	// ChangeReordered is a block moved within the same parent,
+	// relative to its siblings present in both trees
+	ChangeReordered ChangeKind = "reordered"
+
+	// ChangeTypeChanged is a block with a changed type
+	ChangeTypeChanged ChangeKind = "type_changed"
+
+	// ChangeContentChanged is a block with a changed content
+	ChangeContentChanged ChangeKind = "content_changed"
+
+	// ChangeParameterAdded is a parameter set only in the new tree
+	ChangeParameterAdded ChangeKind = "parameter_added"
+
+	// ChangeParameterRemoved is a parameter set only in the old tree
+	ChangeParameterRemoved ChangeKind = "parameter_removed"
+
+	// ChangeParameterModified is a parameter with a changed value
+	ChangeParameterModified ChangeKind = "parameter_modified"
+)
+
+// Change is a single difference between two block trees
+type Change struct {
+	// Kind is the kind of the change
+	Kind ChangeKind
+
+	// BlockID is the ID of the changed block
+	BlockID string
+
+	// Key is the parameter key, for parameter changes
+	Key string
+
+	// OldValue and NewValue are the old and new type for ChangeTypeChanged,
+	// the old and new content for ChangeContentChanged, or the old and new
+	// parameter value for parameter changes
+	OldValue string
+	NewValue string
+
+	// OldParentID and OldIndex are the position in the old tree,
+	// for removed, moved and reordered blocks
+	OldParentID string
+	OldIndex    int
+
+	// NewParentID and NewIndex are the position in the new tree,
+	// for added, moved and reordered blocks
+	NewParentID string
+	NewIndex    int
+}
+
+// Diff compares two block trees, matching blocks by ID,
+// and returns the list of changes from the old to the new tree
+//
+// The changes are ordered: first the removed blocks in pre-order
+// of the old tree, then the changes of the blocks of the new tree
+// in pre-order (position changes, type changes, content changes, and
+// parameter changes sorted by key). Every block of an added or removed
+// subtree is reported
+//
+// If an ID is used more than once in a tree, the first block in
+// pre-order is used. The descendants of the other blocks with the ID
+// are still compared, as children of the first block
+func Diff(oldTree, newTree BlockInterface) []Change {
+	oldIndex := newDiffIndex(oldTree)
+	newIndex := newDiffIndex(newTree)
+
+	changes := []Change{}
+
+	for _, id := range oldIndex.order {
+		if _, exists := newIndex.entries[id]; exists {
+			continue
+		}
+
+		oldEntry := oldIndex.entries[id]
+
+		changes = append(changes, Change{
+			Kind:        ChangeRemoved,
+			BlockID:     id,
+			OldParentID: oldEntry.parentID,
+			OldIndex:    oldEntry.index,
+			NewIndex:    -1,
+		})
+	}
+
+	reordered := findReordered(oldIndex, newIndex)
+
+	for _, id := range newIndex.order {
+		newEntry := newIndex.entries[id]
+		oldEntry, exists := oldIndex.entries[id]
+
+		if !exists {
+			changes = append(changes, Change{
+				Kind:        ChangeAdded,
+				BlockID:     id,
+				OldIndex:    -1,
+				NewParentID: newEntry.parentID,
+				NewIndex:    newEntry.index,
+			})
+			continue
+		}
+
+		position := Change{
+			BlockID:     id,
+			OldParentID: oldEntry.parentID,
+			OldIndex:    oldEntry.index,
+			NewParentID: newEntry.parentID,
+			NewIndex:    newEntry.index,
+		}
+
+		if oldEntry.parentID != newEntry.parentID {
+			position.Kind = ChangeMoved
+			changes = append(changes, position)
+		} else if reordered[id] {
+			position.Kind = ChangeReordered
+			changes = append(changes, position)
+		}
+
+		if oldEntry.block.Type() != newEntry.block.Type() {
+			changes = append(changes, Change{
+				Kind:     ChangeTypeChanged,
+				BlockID:  id,
+				OldValue: oldEntry.block.Type(),
+				NewValue: newEntry.block.Type(),
+			})
+		}
+
+		if oldEntry.block.Content() != newEntry.block.Content() {
+			changes = append(changes, Change{
+				Kind:     ChangeContentChanged,
+				BlockID:  id,
+				OldValue: oldEntry.block.Content(),
+				NewValue: newEntry.block.Content(),
+			})
+		}
+
+		changes = append(changes, diffParameters(id, oldEntry.block.Parameters(), newEntry.block.Parameters())...)
+	}
+
+	return changes
+}
+
+// diffParameters returns the parameter changes of a block, sorted by key
+func diffParameters(id string, oldParameters, newParameters map[string]string) []Change {
+	keys := []string{}
+
+	for key := range oldParameters {
+		keys = append(keys, key)
+	}
+
+	for key := range newParameters {
+		if _, exists := oldParameters[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	changes := []Change{}
+
+	for _, key := range keys {
+		oldValue, oldExists := oldParameters[key]
+		newValue, newExists := newParameters[key]
+
+		change := Change{BlockID: id, Key: key, OldValue: oldValue, NewValue: newValue}
+
+		switch {
+		case !oldExists:
+			change.Kind = ChangeParameterAdded
+		case !newExists:
+			change.Kind = ChangeParameterRemoved
+		case oldValue != newValue:
+			change.Kind = ChangeParameterModified
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// findReordered returns the IDs of the blocks which kept their parent,
+// but changed their order relative to the siblings which also kept
+// their parent. The blocks in the longest common subsequence of the old
+// and new order are considered not reordered
+func findReordered(oldIndex, newIndex diffIndex) map[string]bool {
+	reordered := map[string]bool{}
+
+	for _, parentID := range newIndex.order {
+		if _, exists := oldIndex.entries[parentID]; !exists {
+			continue
+		}
+
+		stays := func(id string) bool {
+			oldEntry, oldExists := oldIndex.entries[id]
+			newEntry, newExists := newIndex.entries[id]
+			return oldExists && newExists && oldEntry.parentID == parentID && newEntry.parentID == parentID
+		}
+
+		oldOrder := slices.DeleteFunc(slices.Clone(oldIndex.childIDs[parentID]), func(id string) bool { return !stays(id) })
+		newOrder := slices.DeleteFunc(slices.Clone(newIndex.childIDs[parentID]), func(id string) bool { return !stays(id) })
+
+		inOrder := longestCommonSubsequence(oldOrder, newOrder)
+
+		for _, id := range newOrder {
+			if !inOrder[id] {
+				reordered[id] = true
+			}
+		}
+	}
+
+	return reordered
+}
+
+// longestCommonSubsequence returns the IDs in the longest
+// common subsequence of the two lists
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	lengths := make([][]int, len(a)+1)
+
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	common := map[string]bool{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return common
+}
+
+// diffIndex indexes the blocks of a tree by ID
+type diffIndex struct {
+	// entries are the blocks keyed by ID
+	entries map[string]diffEntry
+
+	// order are the block IDs in pre-order
+	order []string
+
+	// childIDs are the IDs of the children, keyed by the parent ID
+	childIDs map[string][]string
+}
+
+type diffEntry struct {
+	block    BlockInterface
+	parentID string
+	index    int
+}
+
+func newDiffIndex(root BlockInterface) diffIndex {
+	index := diffIndex{
+		entries:  map[string]diffEntry{},
+		order:    []string{},
+		childIDs: map[string][]string{},
+	}
+
+	index.add(root, "", 0)
+
+	return index
+}
+
+// add indexes the block and its descendants,
+// returns false if the block is nil or its ID is already indexed
+//
+// The block with an ID already indexed is skipped,
+// but its descendants are indexed
+func (index *diffIndex) add(block BlockInterface, parentID string, position int) bool {
+	if block == nil {
+		return false
+	}
+
+	if _, exists := index.entries[block.ID()]; exists {
+		for childIndex, child := range block.Children() {
+			index.add(child, block.ID(), childIndex)
+		}
+
+		return false
+	}
+
+	index.entries[block.ID()] = diffEntry{block: block, parentID: parentID, index: position}
+	index.order = append(index.order, block.ID())
+
+	childIDs := []string{}
+
+	for childIndex, child := range block.Children() {
+		if index.add(child, block.ID(), childIndex) {
+			childIDs = append(childIDs, child.ID())
+		}
+	}
+
+	index.childIDs[block.ID()] = childIDs
+
+	return true
+}
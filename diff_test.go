@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff_NoChanges(t *testing.T) {
+	if changes := Diff(newTestTree(), newTestTree()); len(changes) != 0 {
+		t.Errorf("Diff() = %+v, want no changes", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldTree := newTestTree()
+	FindByID(oldTree, "image1").SetParameter("src", "a.png")
+	FindByID(oldTree, "image1").SetParameter("alt", "A")
+	FindByID(oldTree, "image2").SetParameter("lazy", "true")
+
+	newTree := newTestTree()
+	FindByID(newTree, "image1").SetParameter("src", "b.png")
+	FindByID(newTree, "image1").SetParameter("width", "100")
+	FindByID(newTree, "image2").SetParameter("lazy", "true")
+	FindByID(newTree, "page1").SetType("section")
+	FindByID(newTree, "page1").SetContent("Intro")
+
+	heading := NewBlockBuilder().WithID("heading1").WithType("heading").Build()
+	if err := FindByID(newTree, "page2").InsertChildAt(0, heading); err != nil {
+		t.Fatal(err)
+	}
+	if err := FindByID(newTree, "page1").RemoveChild("paragraph1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveBlock(newTree, "paragraph2", "page2", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveBlock(newTree, "image1", "page2", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{
+		{Kind: ChangeRemoved, BlockID: "paragraph1", OldParentID: "page1", OldIndex: 0, NewIndex: -1},
+		{Kind: ChangeTypeChanged, BlockID: "page1", OldValue: "page", NewValue: "section"},
+		{Kind: ChangeContentChanged, BlockID: "page1", NewValue: "Intro"},
+		{Kind: ChangeAdded, BlockID: "heading1", OldIndex: -1, NewParentID: "page2", NewIndex: 0},
+		{Kind: ChangeReordered, BlockID: "image2", OldParentID: "page2", OldIndex: 0, NewParentID: "page2", NewIndex: 2},
+		{Kind: ChangeMoved, BlockID: "image1", OldParentID: "page1", OldIndex: 1, NewParentID: "page2", NewIndex: 3},
+		{Kind: ChangeParameterRemoved, BlockID: "image1", Key: "alt", OldValue: "A"},
+		{Kind: ChangeParameterModified, BlockID: "image1", Key: "src", OldValue: "a.png", NewValue: "b.png"},
+		{Kind: ChangeParameterAdded, BlockID: "image1", Key: "width", NewValue: "100"},
+	}
+
+	if got := Diff(oldTree, newTree); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiff_InsertionIsNotReorder(t *testing.T) {
+	oldTree := newTestTree()
+	newTree := newTestTree()
+
+	heading := NewBlockBuilder().WithID("heading1").WithType("heading").Build()
+	if err := newTree.InsertChildAt(0, heading); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{
+		{Kind: ChangeAdded, BlockID: "heading1", OldIndex: -1, NewParentID: "document1", NewIndex: 0},
+	}
+
+	if got := Diff(oldTree, newTree); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiff_AddedAndRemovedSubtrees(t *testing.T) {
+	got := Diff(nil, newTestTree())
+
+	if len(got) != 7 {
+		t.Fatalf("expected 7 added blocks, got %d", len(got))
+	}
+
+	for _, change := range got {
+		if change.Kind != ChangeAdded {
+			t.Errorf("expected only added blocks, got %+v", change)
+		}
+	}
+
+	got = Diff(newTestTree(), nil)
+
+	if len(got) != 7 || got[0].Kind != ChangeRemoved || got[0].BlockID != "document1" {
+		t.Errorf("expected 7 removed blocks starting with the root, got %+v", got)
+	}
+}
+
+func TestDiff_DuplicateIDs(t *testing.T) {
+	newTestBlock := func(id string, children ...BlockInterface) BlockInterface {
+		return NewBlockBuilder().WithID(id).WithType("section").WithChildren(children).Build()
+	}
+
+	oldTree := newTestBlock("root", newTestBlock("a"), newTestBlock("a"))
+	newTree := newTestBlock("root", newTestBlock("a"), newTestBlock("a", newTestBlock("c")))
+
+	got := Diff(oldTree, newTree)
+	want := []Change{{Kind: ChangeAdded, BlockID: "c", OldIndex: -1, NewParentID: "a", NewIndex: 0}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+
+	if got := Diff(newTree, oldTree); len(got) != 1 || got[0].Kind != ChangeRemoved || got[0].BlockID != "c" {
+		t.Errorf("Diff() = %+v, want c removed", got)
+	}
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Document is a thread-safe container of a block tree,
+// for many concurrent readers and writers
+//
+// The tree is held as an ImmutableBlock. Readers get it with Snapshot,
+// which never changes once returned, so rendering never sees a half-applied
+// edit. Writes are serialized, and applied copy-on-write: each Update works
+// on a mutable copy of the tree, which replaces the current tree only when
+// the update succeeds
+//
+// Example:
+//
+//	document := ui.NewDocument(root)
+//
+//	// on any goroutine
+//	html, err := renderer.ToHTML(document.Snapshot().ToBlock())
+//
+//	// on the admin goroutine
+//	err := document.Update(func(root ui.BlockInterface) error {
+//		return ui.MoveBlock(root, "paragraph1", "page2", 0)
+//	})
+type Document struct {
+	mu       sync.Mutex
+	snapshot atomic.Pointer[documentSnapshot]
+}
+
+type documentSnapshot struct {
+	root    *ImmutableBlock
+	version uint64
+}
+
+// NewDocument creates a Document holding an immutable copy of the block
+// tree (see ToImmutable), so that later changes of the tree by the caller
+// do not affect it
+func NewDocument(root BlockInterface) *Document {
+	document := &Document{}
+
+	document.snapshot.Store(&documentSnapshot{root: ToImmutable(root), version: 0})
+
+	return document
+}
+
+// Snapshot returns the current block tree
+//
+// The snapshot is shared between readers without copying, and never changes.
+// The blocks left unchanged by an update are shared with the previous
+// snapshot, so values derived from them can be cached by pointer.
+// Use ToBlock for a mutable copy, and Update to make changes
+func (d *Document) Snapshot() *ImmutableBlock {
+	return d.snapshot.Load().root
+}
+
+// Version returns the number of successful updates of the document
+func (d *Document) Version() uint64 {
+	return d.snapshot.Load().version
+}
+
+// Update applies fn to a mutable copy of the current tree, and makes it
+// the current tree if fn returns nil. The tree is stored as an immutable
+// copy, so changes made after fn returns do not affect the document, and
+// the unchanged blocks are shared with the previous snapshot.
+// Updates are serialized
+//
+// If fn returns an error the document is not changed,
+// and the error is returned
+func (d *Document) Update(fn func(root BlockInterface) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := d.snapshot.Load()
+
+	root := current.root.ToBlock()
+
+	if err := fn(root); err != nil {
+		return err
+	}
+
+	d.snapshot.Store(&documentSnapshot{root: toImmutableFrom(root, current.root), version: current.version + 1})
+
+	return nil
+}
+
+// Replace replaces the tree by an immutable copy of the block tree
+func (d *Document) Replace(root BlockInterface) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := d.snapshot.Load()
+
+	d.snapshot.Store(&documentSnapshot{root: toImmutableFrom(root, current.root), version: current.version + 1})
+}
+
+// ToJson returns the JSON of the current tree, as a bare block
+// (to store it in a versioned envelope, see MarshalDocumentEnvelope)
+func (d *Document) ToJson() (string, error) {
+	root := d.Snapshot()
+
+	if root == nil {
+		return "null", nil
+	}
+
+	return root.ToJson()
+}
@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestDocument_Update(t *testing.T) {
+	tree := newTestTree()
+	document := NewDocument(tree)
+
+	before := document.Snapshot()
+
+	err := document.Update(func(root BlockInterface) error {
+		FindByID(root, "image1").SetParameter("src", "a.png")
+		return MoveBlock(root, "image1", "page2", 0)
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	after := document.Snapshot()
+
+	if after.FindByID("image1").Parameter("src") != "a.png" || after.FindByID("page2").Child(0).ID() != "image1" {
+		t.Errorf("update not applied: %s", mustToJson(t, after.ToBlock()))
+	}
+
+	if before.FindByID("image1").Parameter("src") != "" || before.FindByID("page1").FindByID("image1") == nil {
+		t.Errorf("the previous snapshot must not change: %s", mustToJson(t, before.ToBlock()))
+	}
+
+	if document.Version() != 1 {
+		t.Errorf("Version() = %d, want 1", document.Version())
+	}
+
+	tree.SetParameter("changed", "yes")
+
+	if document.Snapshot().HasParameter("changed") {
+		t.Error("changes of the original tree must not affect the document")
+	}
+}
+
+func TestDocument_UpdateError(t *testing.T) {
+	document := NewDocument(newTestTree())
+	before := mustToJson(t, document.Snapshot().ToBlock())
+
+	errFailed := errors.New("failed")
+
+	err := document.Update(func(root BlockInterface) error {
+		root.SetType("changed")
+		return errFailed
+	})
+
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected errFailed, got %v", err)
+	}
+
+	if got := mustToJson(t, document.Snapshot().ToBlock()); got != before {
+		t.Errorf("failed update changed the document: %s", got)
+	}
+
+	if document.Version() != 0 {
+		t.Errorf("Version() = %d, want 0", document.Version())
+	}
+}
+
+func TestDocument_UpdateSharesUnchangedBlocks(t *testing.T) {
+	document := NewDocument(newTestTree())
+	before := document.Snapshot()
+
+	err := document.Update(func(root BlockInterface) error {
+		FindByID(root, "image1").SetParameter("src", "a.png")
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	after := document.Snapshot()
+
+	if after == before || after.FindByID("page1") == before.FindByID("page1") {
+		t.Error("the blocks on the path to the changed block must be copied")
+	}
+
+	if after.FindByID("page2") != before.FindByID("page2") || after.FindByID("paragraph1") != before.FindByID("paragraph1") {
+		t.Error("unchanged blocks must be shared with the previous snapshot")
+	}
+
+	if err := document.Update(func(BlockInterface) error { return nil }); err != nil || document.Snapshot() != after {
+		t.Errorf("an update without changes must keep the snapshot, got %v", err)
+	}
+}
+
+func TestDocument_UpdateDoesNotPublishTheRootOfFn(t *testing.T) {
+	document := NewDocument(newTestTree())
+
+	var kept BlockInterface
+
+	err := document.Update(func(root BlockInterface) error {
+		kept = root
+		root.SetParameter("title", "Home")
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kept.SetParameter("title", "hacked")
+	FindByID(kept, "page1").SetType("hacked")
+
+	snapshot := document.Snapshot()
+
+	if snapshot.Parameter("title") != "Home" || snapshot.FindByID("page1").Type() != "page" {
+		t.Errorf("changes after Update must not affect the document: %s", mustToJson(t, snapshot.ToBlock()))
+	}
+}
+
+func TestDocument_Replace(t *testing.T) {
+	document := NewDocument(newTestTree())
+
+	page := NewBlockBuilder().WithID("page9").WithType("page").Build()
+	document.Replace(page)
+
+	if document.Snapshot().ID() != "page9" || document.Version() != 1 {
+		t.Errorf("unexpected document after Replace: %s", mustToJson(t, document.Snapshot().ToBlock()))
+	}
+
+	documentJson, err := document.ToJson()
+
+	if err != nil || documentJson != mustToJson(t, page) {
+		t.Errorf("ToJson() = %s, %v", documentJson, err)
+	}
+}
+
+func TestDocument_Concurrent(t *testing.T) {
+	document := NewDocument(newTestTree())
+	renderer := NewRenderer()
+
+	const writers = 5
+	const updates = 20
+
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				_ = document.Update(func(root BlockInterface) error {
+					root.AddChild(NewBlockBuilder().WithID("w" + strconv.Itoa(w) + "_" + strconv.Itoa(i)).WithType("page").Build())
+					return nil
+				})
+			}
+		}(w)
+	}
+
+	for r := 0; r < 10; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				snapshot := document.Snapshot()
+				count := snapshot.ChildCount()
+				if _, err := renderer.ToHTML(snapshot.ToBlock()); err != nil {
+					t.Error(err)
+				}
+				if _, err := snapshot.ToJson(); err != nil {
+					t.Error(err)
+				}
+				if snapshot.ChildCount() != count {
+					t.Error("snapshot changed while reading")
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if got := document.Snapshot().ChildCount(); got != 2+writers*updates {
+		t.Errorf("children = %d, want %d", got, 2+writers*updates)
+	}
+
+	if document.Version() != writers*updates {
+		t.Errorf("Version() = %d, want %d", document.Version(), writers*updates)
+	}
+}
@@ -2,11 +2,11 @@ package ui
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 )
 
 func MarshalBlocksToJson(blocks []BlockInterface) (string, error) {
-	blocksMap := []blockJsonObject{}
+	blocksMap := []BlockJsonObject{}
 
 	for _, block := range blocks {
 		blocksMap = append(blocksMap, block.ToJsonObject())
@@ -17,25 +17,36 @@ func MarshalBlocksToJson(blocks []BlockInterface) (string, error) {
 	return string(blocksJson), err
 }
 
+// UnmarshalJsonToBlocks unmarshals a JSON array of blocks
+//
+// Returns a *DecodeError with the path of the offending field,
+// if any of the blocks is not valid
+//
+// No decode limits are enforced, to decode JSON from untrusted
+// sources use UnmarshalJsonToBlocksWithOptions
 func UnmarshalJsonToBlocks(blocksJson string) ([]BlockInterface, error) {
-	blocksMap := []map[string]any{}
+	return unmarshalJsonToBlocks([]byte(blocksJson), "$", &decodeState{})
+}
+
+// unmarshalJsonToBlocks unmarshals a JSON array of blocks at the path,
+// enforcing the decode limits of the state
+func unmarshalJsonToBlocks(blocksJson []byte, path string, state *decodeState) ([]BlockInterface, error) {
+	d := newBlockDecoder(blocksJson, state)
 
-	err := json.Unmarshal([]byte(blocksJson), &blocksMap)
+	token, err := d.token()
 
 	if err != nil {
 		return nil, err
 	}
 
-	blocks := []BlockInterface{}
+	blocks, err := d.decodeBlockList(token, path, 1)
 
-	for _, blockMap := range blocksMap {
-		block, err := ConvertMapToBlock(blockMap)
-
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		blocks = append(blocks, block)
+	if err := d.end(); err != nil {
+		return nil, err
 	}
 
 	return blocks, nil
@@ -64,7 +75,8 @@ func ConvertBlocksToMap(blocks []BlockInterface) []map[string]any {
 // ConvertMapToBlock converts a map to a block
 //
 // The map must represent a valid block (have parameters like id, and type),
-// otherwise an error will be returned
+// otherwise a *DecodeError with the path of the offending field
+// will be returned
 //
 // Parameters:
 // - blockMap - a map[string]any to convert to a block
@@ -73,7 +85,7 @@ func ConvertBlocksToMap(blocks []BlockInterface) []map[string]any {
 // - BlockInterface - a block
 // - error - if the map[string]any is not a valid block
 func ConvertMapToBlock(blockMap map[string]any) (BlockInterface, error) {
-	blockMap, err := mapToBlockMap(blockMap)
+	blockMap, err := mapToBlockMap(blockMap, "$")
 
 	if err != nil {
 		return nil, err
@@ -83,65 +95,108 @@ func ConvertMapToBlock(blockMap map[string]any) (BlockInterface, error) {
 }
 
 // mapToBlockMap converts a map[string]any to a map[string]any
-// the map[string]any must be a valid block, otherwise an error
-// will be returned
+// the map[string]any must be a valid block, otherwise a *DecodeError
+// will be returned. The input map is not modified
 //
 // Parameters:
 // - blockMap - a map[string]any to convert to a block
+// - path - the JSON path of the block, used in errors
 //
 // Returns:
 // - map[string]any - a block
 // - error - if the map[string]any is not a valid block
-func mapToBlockMap(blockMap map[string]any) (map[string]any, error) {
-	idAny, ok := blockMap["id"]
+func mapToBlockMap(blockMap map[string]any, path string) (map[string]any, error) {
+	id, blockType, content, err := blockStringFields(blockMap, path)
 
-	if !ok {
-		return nil, errors.New("id not found")
+	if err != nil {
+		return nil, err
 	}
 
-	typeAny, ok := blockMap["type"]
+	parameters, err := mapToParameters(blockMap["parameters"], path+".parameters")
 
-	if !ok {
-		return nil, errors.New("type not found")
+	if err != nil {
+		return nil, err
 	}
 
-	parametersAny, ok := blockMap["parameters"]
+	childrenAny, err := mapToChildren(blockMap["children"], path+".children")
 
-	if !ok {
-		parametersAny = map[string]any{}
+	if err != nil {
+		return nil, err
 	}
 
-	childrenAny, ok := blockMap["children"]
+	children := []map[string]any{}
 
-	if !ok {
-		childrenAny = []any{}
-	}
+	for index, childAny := range childrenAny {
+		childPath := fmt.Sprintf("%s.children[%d]", path, index)
+
+		childMap, ok := childAny.(map[string]any)
 
-	childrenArrayAny := childrenAny.([]any)
+		if !ok {
+			return nil, invalidFieldType(childPath, "object", childAny)
+		}
 
-	childrenMap := []map[string]any{}
-	for _, childAny := range childrenArrayAny {
-		childAny := childAny.(map[string]any)
-		child, err := mapToBlockMap(childAny)
+		child, err := mapToBlockMap(childMap, childPath)
 
 		if err != nil {
 			return nil, err
 		}
 
-		childrenMap = append(childrenMap, child)
+		children = append(children, child)
 	}
 
-	parametersMapAny := parametersAny.(map[string]any)
-	parametersMap := map[string]string{}
+	return map[string]any{
+		"id":         id,
+		"type":       blockType,
+		"content":    content,
+		"parameters": parameters,
+		"children":   children,
+	}, nil
+}
+
+// mapToParameters converts the parameters field of a block map,
+// a missing or null field is an empty map
+func mapToParameters(parametersAny any, path string) (map[string]string, error) {
+	parameters := map[string]string{}
+
+	switch parametersTyped := parametersAny.(type) {
+	case nil:
+		return parameters, nil
+	case map[string]string:
+		for k, v := range parametersTyped {
+			parameters[k] = v
+		}
+		return parameters, nil
+	case map[string]any:
+		for k, v := range parametersTyped {
+			value, err := blockParameterValue(v, path+"."+k)
 
-	for k, v := range parametersMapAny {
-		parametersMap[k] = v.(string)
-	}
+			if err != nil {
+				return nil, err
+			}
 
-	blockMap["id"] = idAny.(string)
-	blockMap["type"] = typeAny.(string)
-	blockMap["parameters"] = parametersMap
-	blockMap["children"] = childrenMap
+			parameters[k] = value
+		}
+		return parameters, nil
+	default:
+		return nil, invalidFieldType(path, "object", parametersAny)
+	}
+}
 
-	return blockMap, nil
+// mapToChildren converts the children field of a block map to a slice,
+// a missing or null field is an empty slice
+func mapToChildren(childrenAny any, path string) ([]any, error) {
+	switch childrenTyped := childrenAny.(type) {
+	case nil:
+		return []any{}, nil
+	case []any:
+		return childrenTyped, nil
+	case []map[string]any:
+		children := make([]any, 0, len(childrenTyped))
+		for _, child := range childrenTyped {
+			children = append(children, child)
+		}
+		return children, nil
+	default:
+		return nil, invalidFieldType(path, "array", childrenAny)
+	}
 }
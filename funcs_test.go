@@ -0,0 +1,233 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewBlockFromJson_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		wantErr  error
+		wantPath string
+	}{
+		{
+			name:     "not an object",
+			json:     `[]`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$",
+		},
+		{
+			name:     "missing id",
+			json:     `{"type":"a"}`,
+			wantErr:  ErrMissingField,
+			wantPath: "$.id",
+		},
+		{
+			name:     "numeric id",
+			json:     `{"id":5,"type":"a"}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.id",
+		},
+		{
+			name:     "missing type",
+			json:     `{"id":"1"}`,
+			wantErr:  ErrMissingField,
+			wantPath: "$.type",
+		},
+		{
+			name:     "numeric content",
+			json:     `{"id":"1","type":"a","content":5}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.content",
+		},
+		{
+			name:     "numeric parameter value",
+			json:     `{"id":"1","type":"a","parameters":{"width":100}}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.parameters.width",
+		},
+		{
+			name:     "parameters not an object",
+			json:     `{"id":"1","type":"a","parameters":[]}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.parameters",
+		},
+		{
+			name:     "children not an array",
+			json:     `{"id":"1","type":"a","children":{}}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.children",
+		},
+		{
+			name:     "child not an object",
+			json:     `{"id":"1","type":"a","children":["2"]}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.children[0]",
+		},
+		{
+			name:     "nested child error",
+			json:     `{"id":"1","type":"a","children":[{"id":"2","type":"b"},{"id":"3","type":"b","parameters":{"x":true}}]}`,
+			wantErr:  ErrInvalidFieldType,
+			wantPath: "$.children[1].parameters.x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBlockFromJson(tt.json)
+
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("expected *DecodeError, got %v", err)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if decodeErr.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", decodeErr.Path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestNewBlockFromJson_NullFields(t *testing.T) {
+	block, err := NewBlockFromJson(`{"id":"1","type":"a","parameters":null,"children":null}`)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(block.Parameters()) != 0 || len(block.Children()) != 0 {
+		t.Errorf("expected empty parameters and children, got %v", block.ToMap())
+	}
+}
+
+func TestUnmarshalJsonToBlocks_DecodeErrors(t *testing.T) {
+	_, err := UnmarshalJsonToBlocks(`[{"id":"1","type":"a"},5]`)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+
+	if decodeErr.Path != "$[1]" {
+		t.Errorf("Path = %q, want %q", decodeErr.Path, "$[1]")
+	}
+
+	_, err = UnmarshalJsonToBlocks(`[{"id":"1","type":"a","children":[{"id":2}]}]`)
+
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+
+	if decodeErr.Path != "$[0].children[0].id" {
+		t.Errorf("Path = %q, want %q", decodeErr.Path, "$[0].children[0].id")
+	}
+}
+
+func TestConvertMapToBlock_DoesNotModifyInput(t *testing.T) {
+	blockMap := map[string]any{
+		"id":         "1",
+		"type":       "a",
+		"parameters": map[string]any{"key": "value"},
+	}
+
+	if _, err := ConvertMapToBlock(blockMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := blockMap["children"]; ok {
+		t.Error("ConvertMapToBlock must not modify the input map")
+	}
+}
+
+func TestConvertMapToBlock_Content(t *testing.T) {
+	block, err := ConvertMapToBlock(map[string]any{"id": "1", "type": "a", "content": "Hello"})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if block.Content() != "Hello" {
+		t.Errorf("Content() = %q, want %q", block.Content(), "Hello")
+	}
+
+	_, err = ConvertMapToBlock(map[string]any{"id": "1", "type": "a", "content": 5})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Path != "$.content" || !errors.Is(err, ErrInvalidFieldType) {
+		t.Errorf("expected an invalid $.content, got %v", err)
+	}
+}
+
+func TestNewBlockFromMap_InvalidFields(t *testing.T) {
+	block := NewBlockFromMap(map[string]any{
+		"id":         5,
+		"type":       "a",
+		"parameters": map[string]any{"valid": "yes", "invalid": 5},
+		"children":   []any{"invalid", map[string]any{"id": "2", "type": "b"}},
+	})
+
+	if block.ID() != "" {
+		t.Errorf("ID() = %q, want empty", block.ID())
+	}
+
+	if len(block.Parameters()) != 1 || block.Parameter("valid") != "yes" {
+		t.Errorf("Parameters() = %v, want only the valid parameter", block.Parameters())
+	}
+
+	if len(block.Children()) != 1 || block.Children()[0].ID() != "2" {
+		t.Errorf("Children() = %v, want only the valid child", block.Children())
+	}
+
+	if NewBlockFromMap(map[string]any{"children": nil}) == nil {
+		t.Error("expected a block for nil children")
+	}
+}
+
+var fuzzBlockJsonSeeds = []string{
+	``,
+	`null`,
+	`5`,
+	`"block"`,
+	`[]`,
+	`{}`,
+	`{"id":"1","type":"a","content":"","parameters":{"key":"value"},"children":[]}`,
+	`{"id":"1","type":"a","parameters":{"key":"value"},"children":[{"id":"2","type":"b","parameters":{},"children":[]}]}`,
+	`{"id":5,"type":"a"}`,
+	`{"id":"1","type":null}`,
+	`{"id":"1","type":"a","parameters":{"width":100}}`,
+	`{"id":"1","type":"a","parameters":"x"}`,
+	`{"id":"1","type":"a","children":{}}`,
+	`{"id":"1","type":"a","children":[null]}`,
+	`{"id":"1","type":"a","children":[[]]}`,
+	`{"id":"1","type":"a","children":[{"id":"2"}]}`,
+}
+
+func FuzzNewBlockFromJson(f *testing.F) {
+	for _, seed := range fuzzBlockJsonSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, blockJson string) {
+		block, err := NewBlockFromJson(blockJson)
+
+		if err == nil && block == nil {
+			t.Error("expected a block or an error")
+		}
+	})
+}
+
+func FuzzUnmarshalJsonToBlocks(f *testing.F) {
+	for _, seed := range fuzzBlockJsonSeeds {
+		f.Add("[" + seed + "]")
+	}
+
+	f.Fuzz(func(t *testing.T, blocksJson string) {
+		_, _ = UnmarshalJsonToBlocks(blocksJson)
+	})
+}
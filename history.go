@@ -0,0 +1,510 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrNothingToUndo is returned by History.Undo when there is nothing to undo
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// ErrNothingToRedo is returned by History.Redo when there is nothing to redo
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// History wraps a block tree, and records the mutations made through it
+// as invertible commands, which can be undone and redone
+//
+// Only the mutations made through the History are recorded, the tree must
+// not be modified directly while the History is in use. History is not
+// safe for concurrent use
+//
+// Example:
+//
+//	history := ui.NewHistory(document, 100)
+//	err := history.SetParameter("paragraph1", "content", "Hello")
+//	err = history.Undo()
+type History struct {
+	root        BlockInterface
+	limit       int
+	undoStack   [][]historyCommand
+	redoStack   [][]historyCommand
+	transaction []historyCommand
+	inTx        bool
+}
+
+// NewHistory creates a History for the block tree, keeping up to
+// limit undoable entries (0 for no limit)
+func NewHistory(root BlockInterface, limit int) *History {
+	return &History{
+		root:      root,
+		limit:     limit,
+		undoStack: [][]historyCommand{},
+		redoStack: [][]historyCommand{},
+	}
+}
+
+// Root returns the root block of the tree
+func (h *History) Root() BlockInterface {
+	return h.root
+}
+
+// CanUndo returns true if there is an entry to undo
+func (h *History) CanUndo() bool {
+	return len(h.undoStack) > 0
+}
+
+// CanRedo returns true if there is an entry to redo
+func (h *History) CanRedo() bool {
+	return len(h.redoStack) > 0
+}
+
+// SetParameter sets a parameter of the block with the ID
+func (h *History) SetParameter(id string, key string, value string) error {
+	block, err := h.find(id)
+
+	if err != nil {
+		return err
+	}
+
+	oldValue, existed := block.Parameters()[key]
+
+	return h.execute(&setParameterCommand{id: id, key: key, oldValue: oldValue, existed: existed, newValue: value, exists: true})
+}
+
+// RemoveParameter removes a parameter of the block with the ID,
+// nothing is recorded if the parameter is not set
+func (h *History) RemoveParameter(id string, key string) error {
+	block, err := h.find(id)
+
+	if err != nil {
+		return err
+	}
+
+	oldValue, existed := block.Parameters()[key]
+
+	if !existed {
+		return nil
+	}
+
+	return h.execute(&setParameterCommand{id: id, key: key, oldValue: oldValue, existed: existed})
+}
+
+// SetType sets the type of the block with the ID
+func (h *History) SetType(id string, blockType string) error {
+	block, err := h.find(id)
+
+	if err != nil {
+		return err
+	}
+
+	return h.execute(&setTypeCommand{id: id, oldType: block.Type(), newType: blockType})
+}
+
+// SetContent sets the content of the block with the ID
+func (h *History) SetContent(id string, content string) error {
+	block, err := h.find(id)
+
+	if err != nil {
+		return err
+	}
+
+	return h.execute(&setContentCommand{id: id, oldContent: block.Content(), newContent: content})
+}
+
+// AddChild appends the child to the block with the parent ID
+func (h *History) AddChild(parentID string, child BlockInterface) error {
+	parent, err := h.find(parentID)
+
+	if err != nil {
+		return err
+	}
+
+	return h.InsertChildAt(parentID, len(parent.Children()), child)
+}
+
+// InsertChildAt inserts the child at the index of the block with the parent ID
+//
+// Returns ErrNilBlock if the child is nil, and ErrBlockExists if the child
+// (or one of its descendants) is already in the tree, or uses an ID
+// which is already used in the tree
+func (h *History) InsertChildAt(parentID string, index int, child BlockInterface) error {
+	if child == nil {
+		return fmt.Errorf("%w: child of %q", ErrNilBlock, parentID)
+	}
+
+	if err := checkNotInTree(h.root, child); err != nil {
+		return err
+	}
+
+	return h.execute(&insertChildCommand{parentID: parentID, index: index, child: child})
+}
+
+// MoveBlock moves the block with the ID to the new parent, at the index (see MoveBlock)
+func (h *History) MoveBlock(id string, newParentID string, index int) error {
+	oldParent, oldIndex, err := findParentAndIndex(h.root, id)
+
+	if err != nil {
+		return err
+	}
+
+	return h.execute(&moveCommand{
+		id:          id,
+		oldParentID: oldParent.ID(),
+		oldIndex:    oldIndex,
+		newParentID: newParentID,
+		newIndex:    index,
+	})
+}
+
+// RemoveBlock removes the block with the ID from its parent
+func (h *History) RemoveBlock(id string) error {
+	parent, index, err := findParentAndIndex(h.root, id)
+
+	if err != nil {
+		return err
+	}
+
+	return h.execute(&removeBlockCommand{parentID: parent.ID(), index: index, block: parent.Children()[index]})
+}
+
+// Transaction groups the edits made in fn into one entry,
+// which is undone and redone at once
+//
+// If fn returns an error, the edits made so far are reverted, and the
+// error is returned. If they can not be reverted, they are kept, and
+// recorded as an entry. Nested transactions are part of the outer transaction
+func (h *History) Transaction(fn func() error) error {
+	if h.inTx {
+		return fn()
+	}
+
+	h.inTx = true
+	h.transaction = []historyCommand{}
+
+	err := fn()
+
+	commands := h.transaction
+	h.inTx = false
+	h.transaction = nil
+
+	if err != nil {
+		if revertErr := revertCommands(h.root, commands); revertErr != nil {
+			// the edits are applied again, record them to keep them undoable
+			h.push(commands)
+			return errors.Join(err, revertErr)
+		}
+		return err
+	}
+
+	if len(commands) > 0 {
+		h.push(commands)
+	}
+
+	return nil
+}
+
+// Undo reverts the last entry
+//
+// If the entry can not be reverted (i.e. the tree was modified directly),
+// the commands already reverted are applied again, and the entry is kept
+// on the undo stack
+func (h *History) Undo() error {
+	if len(h.undoStack) == 0 {
+		return ErrNothingToUndo
+	}
+
+	commands := h.undoStack[len(h.undoStack)-1]
+
+	if err := revertCommands(h.root, commands); err != nil {
+		return err
+	}
+
+	h.undoStack = h.undoStack[:len(h.undoStack)-1]
+	h.redoStack = append(h.redoStack, commands)
+
+	return nil
+}
+
+// Redo reapplies the last undone entry
+//
+// If the entry can not be reapplied, the commands already applied
+// are reverted, and the entry is kept on the redo stack
+func (h *History) Redo() error {
+	if len(h.redoStack) == 0 {
+		return ErrNothingToRedo
+	}
+
+	commands := h.redoStack[len(h.redoStack)-1]
+
+	if err := applyCommands(h.root, commands); err != nil {
+		return err
+	}
+
+	h.redoStack = h.redoStack[:len(h.redoStack)-1]
+	h.undoStack = append(h.undoStack, commands)
+
+	return nil
+}
+
+// execute applies the command, and records it
+func (h *History) execute(command historyCommand) error {
+	if err := command.apply(h.root); err != nil {
+		return err
+	}
+
+	if h.inTx {
+		h.transaction = append(h.transaction, command)
+		return nil
+	}
+
+	h.push([]historyCommand{command})
+
+	return nil
+}
+
+// push adds an entry to the undo stack, and clears the redo stack
+func (h *History) push(commands []historyCommand) {
+	h.undoStack = append(h.undoStack, commands)
+	h.redoStack = [][]historyCommand{}
+
+	if h.limit > 0 && len(h.undoStack) > h.limit {
+		h.undoStack = slices.Delete(h.undoStack, 0, len(h.undoStack)-h.limit)
+	}
+}
+
+func (h *History) find(id string) (BlockInterface, error) {
+	return findBlock(h.root, id)
+}
+
+// revertCommands reverts the commands in reverse order
+//
+// If a command fails, the commands already reverted are applied again
+func revertCommands(root BlockInterface, commands []historyCommand) error {
+	for index, command := range slices.Backward(commands) {
+		if err := command.revert(root); err != nil {
+			for _, reverted := range commands[index+1:] {
+				if applyErr := reverted.apply(root); applyErr != nil {
+					return errors.Join(err, applyErr)
+				}
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyCommands applies the commands in order
+//
+// If a command fails, the commands already applied are reverted
+func applyCommands(root BlockInterface, commands []historyCommand) error {
+	for index, command := range commands {
+		if err := command.apply(root); err != nil {
+			for _, applied := range slices.Backward(commands[:index]) {
+				if revertErr := applied.revert(root); revertErr != nil {
+					return errors.Join(err, revertErr)
+				}
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findBlock returns the block with the ID, or ErrBlockNotFound
+func findBlock(root BlockInterface, id string) (BlockInterface, error) {
+	block := FindByID(root, id)
+
+	if block == nil {
+		return nil, fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	return block, nil
+}
+
+// removeChildAt removes the child at the index of the block with the parent ID
+//
+// The child is matched by identity, not by ID, so a sibling with the same ID
+// is never removed. Returns ErrBlockNotFound if the child is not at the index
+func removeChildAt(root BlockInterface, parentID string, index int, child BlockInterface) error {
+	parent, err := findBlock(root, parentID)
+
+	if err != nil {
+		return err
+	}
+
+	children := parent.Children()
+
+	if index < 0 || index >= len(children) || children[index] != child {
+		return fmt.Errorf("%w: %q at index %d of %q", ErrBlockNotFound, child.ID(), index, parentID)
+	}
+
+	parent.SetChildren(slices.Delete(slices.Clone(children), index, index+1))
+
+	return nil
+}
+
+// == COMMANDS ================================================================
+
+// historyCommand is an invertible mutation of a block tree
+type historyCommand interface {
+	apply(root BlockInterface) error
+	revert(root BlockInterface) error
+}
+
+type setParameterCommand struct {
+	id       string
+	key      string
+	oldValue string
+	existed  bool
+	newValue string
+	exists   bool
+}
+
+func (c *setParameterCommand) apply(root BlockInterface) error {
+	return setOrRemoveParameter(root, c.id, c.key, c.newValue, c.exists)
+}
+
+func (c *setParameterCommand) revert(root BlockInterface) error {
+	return setOrRemoveParameter(root, c.id, c.key, c.oldValue, c.existed)
+}
+
+func setOrRemoveParameter(root BlockInterface, id string, key string, value string, set bool) error {
+	block, err := findBlock(root, id)
+
+	if err != nil {
+		return err
+	}
+
+	if set {
+		block.SetParameter(key, value)
+		return nil
+	}
+
+	parameters := maps.Clone(block.Parameters())
+	delete(parameters, key)
+	block.SetParameters(parameters)
+
+	return nil
+}
+
+type setTypeCommand struct {
+	id      string
+	oldType string
+	newType string
+}
+
+func (c *setTypeCommand) apply(root BlockInterface) error {
+	block, err := findBlock(root, c.id)
+
+	if err != nil {
+		return err
+	}
+
+	block.SetType(c.newType)
+
+	return nil
+}
+
+func (c *setTypeCommand) revert(root BlockInterface) error {
+	block, err := findBlock(root, c.id)
+
+	if err != nil {
+		return err
+	}
+
+	block.SetType(c.oldType)
+
+	return nil
+}
+
+type setContentCommand struct {
+	id         string
+	oldContent string
+	newContent string
+}
+
+func (c *setContentCommand) apply(root BlockInterface) error {
+	block, err := findBlock(root, c.id)
+
+	if err != nil {
+		return err
+	}
+
+	block.SetContent(c.newContent)
+
+	return nil
+}
+
+func (c *setContentCommand) revert(root BlockInterface) error {
+	block, err := findBlock(root, c.id)
+
+	if err != nil {
+		return err
+	}
+
+	block.SetContent(c.oldContent)
+
+	return nil
+}
+
+type insertChildCommand struct {
+	parentID string
+	index    int
+	child    BlockInterface
+}
+
+func (c *insertChildCommand) apply(root BlockInterface) error {
+	parent, err := findBlock(root, c.parentID)
+
+	if err != nil {
+		return err
+	}
+
+	return parent.InsertChildAt(c.index, c.child)
+}
+
+func (c *insertChildCommand) revert(root BlockInterface) error {
+	return removeChildAt(root, c.parentID, c.index, c.child)
+}
+
+type moveCommand struct {
+	id          string
+	oldParentID string
+	oldIndex    int
+	newParentID string
+	newIndex    int
+}
+
+func (c *moveCommand) apply(root BlockInterface) error {
+	return MoveBlock(root, c.id, c.newParentID, c.newIndex)
+}
+
+func (c *moveCommand) revert(root BlockInterface) error {
+	return MoveBlock(root, c.id, c.oldParentID, c.oldIndex)
+}
+
+type removeBlockCommand struct {
+	parentID string
+	index    int
+	block    BlockInterface
+}
+
+func (c *removeBlockCommand) apply(root BlockInterface) error {
+	return removeChildAt(root, c.parentID, c.index, c.block)
+}
+
+func (c *removeBlockCommand) revert(root BlockInterface) error {
+	parent, err := findBlock(root, c.parentID)
+
+	if err != nil {
+		return err
+	}
+
+	return parent.InsertChildAt(c.index, c.block)
+}
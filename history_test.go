@@ -0,0 +1,323 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestHistory_UndoRedo(t *testing.T) {
+	tree := newTestTree()
+	FindByID(tree, "image1").SetParameter("src", "a.png")
+	original := mustToJson(t, tree)
+
+	history := NewHistory(tree, 0)
+
+	steps := []func() error{
+		func() error { return history.SetParameter("image1", "src", "b.png") },
+		func() error { return history.SetParameter("image1", "alt", "An image") },
+		func() error { return history.RemoveParameter("image1", "src") },
+		func() error { return history.SetType("page1", "section") },
+		func() error { return history.SetContent("paragraph1", "Hello") },
+		func() error {
+			return history.AddChild("page1", NewBlockBuilder().WithID("heading1").WithType("heading").Build())
+		},
+		func() error {
+			return history.InsertChildAt("page2", 0, NewBlockBuilder().WithID("heading2").WithType("heading").Build())
+		},
+		func() error { return history.MoveBlock("paragraph1", "page2", 1) },
+		func() error { return history.RemoveBlock("image2") },
+	}
+
+	states := []string{original}
+
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		states = append(states, mustToJson(t, tree))
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		if err := history.Undo(); err != nil {
+			t.Fatalf("undo %d: unexpected error: %v", i, err)
+		}
+
+		if got := mustToJson(t, tree); got != states[i] {
+			t.Errorf("after undo %d = %s, want %s", i, got, states[i])
+		}
+	}
+
+	if err := history.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	for i := range steps {
+		if err := history.Redo(); err != nil {
+			t.Fatalf("redo %d: unexpected error: %v", i, err)
+		}
+
+		if got := mustToJson(t, tree); got != states[i+1] {
+			t.Errorf("after redo %d = %s, want %s", i, got, states[i+1])
+		}
+	}
+
+	if err := history.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("expected ErrNothingToRedo, got %v", err)
+	}
+}
+
+func TestHistory_NewEditClearsRedo(t *testing.T) {
+	history := NewHistory(newTestTree(), 0)
+
+	if err := history.SetType("page1", "section"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := history.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !history.CanRedo() {
+		t.Fatal("expected CanRedo after undo")
+	}
+
+	if err := history.SetType("page2", "section"); err != nil {
+		t.Fatal(err)
+	}
+
+	if history.CanRedo() {
+		t.Error("a new edit must clear the redo stack")
+	}
+}
+
+func TestHistory_Transaction(t *testing.T) {
+	tree := newTestTree()
+	original := mustToJson(t, tree)
+	history := NewHistory(tree, 0)
+
+	err := history.Transaction(func() error {
+		if err := history.SetParameter("page1", "title", "One"); err != nil {
+			return err
+		}
+		return history.Transaction(func() error {
+			return history.MoveBlock("image1", "page2", 0)
+		})
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := history.Undo(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := mustToJson(t, tree); got != original {
+		t.Errorf("undo of a transaction = %s, want %s", got, original)
+	}
+
+	if history.CanUndo() {
+		t.Error("a transaction must be a single entry")
+	}
+}
+
+func TestHistory_TransactionRollback(t *testing.T) {
+	tree := newTestTree()
+	original := mustToJson(t, tree)
+	history := NewHistory(tree, 0)
+
+	err := history.Transaction(func() error {
+		if err := history.SetParameter("page1", "title", "One"); err != nil {
+			return err
+		}
+		return history.MoveBlock("page1", "image1", 0)
+	})
+
+	if !errors.Is(err, ErrCyclicMove) {
+		t.Fatalf("expected ErrCyclicMove, got %v", err)
+	}
+
+	if got := mustToJson(t, tree); got != original {
+		t.Errorf("failed transaction = %s, want %s", got, original)
+	}
+
+	if history.CanUndo() {
+		t.Error("a failed transaction must not be recorded")
+	}
+}
+
+func TestHistory_FailedUndoKeepsTheEntry(t *testing.T) {
+	tree := newTestTree()
+	history := NewHistory(tree, 0)
+
+	err := history.Transaction(func() error {
+		if err := history.SetParameter("paragraph1", "title", "One"); err != nil {
+			return err
+		}
+		return history.AddChild("page2", NewBlockBuilder().WithID("heading1").WithType("heading").Build())
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// modified directly, so reverting the parameter of paragraph1 fails
+	_ = FindByID(tree, "page1").RemoveChild("paragraph1")
+	before := mustToJson(t, tree)
+
+	if err := history.Undo(); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if got := mustToJson(t, tree); got != before {
+		t.Errorf("failed undo changed the tree: %s, want %s", got, before)
+	}
+
+	if !history.CanUndo() || history.CanRedo() {
+		t.Errorf("failed undo must keep the entry, CanUndo() = %v, CanRedo() = %v", history.CanUndo(), history.CanRedo())
+	}
+}
+
+func TestHistory_FailedRedoKeepsTheEntry(t *testing.T) {
+	tree := newTestTree()
+	history := NewHistory(tree, 0)
+
+	err := history.Transaction(func() error {
+		if err := history.AddChild("page2", NewBlockBuilder().WithID("heading1").WithType("heading").Build()); err != nil {
+			return err
+		}
+		return history.SetParameter("paragraph1", "title", "One")
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := history.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	// modified directly, so setting the parameter of paragraph1 fails
+	_ = FindByID(tree, "page1").RemoveChild("paragraph1")
+	before := mustToJson(t, tree)
+
+	if err := history.Redo(); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if got := mustToJson(t, tree); got != before {
+		t.Errorf("failed redo changed the tree: %s, want %s", got, before)
+	}
+
+	if history.CanUndo() || !history.CanRedo() {
+		t.Errorf("failed redo must keep the entry, CanUndo() = %v, CanRedo() = %v", history.CanUndo(), history.CanRedo())
+	}
+}
+
+func TestHistory_InsertRejectsBlocksInTheTree(t *testing.T) {
+	tree := newTestTree()
+	history := NewHistory(tree, 0)
+	original := mustToJson(t, tree)
+
+	children := []BlockInterface{
+		NewBlockBuilder().WithID("paragraph1").WithType("paragraph").Build(),
+		FindByID(tree, "image1"),
+		NewBlockBuilder().WithID("section1").WithType("section").WithChildren([]BlockInterface{NewBlockBuilder().WithID("page2").Build()}).Build(),
+	}
+
+	for _, child := range children {
+		if err := history.InsertChildAt("page1", 0, child); !errors.Is(err, ErrBlockExists) {
+			t.Errorf("InsertChildAt(%q) expected ErrBlockExists, got %v", child.ID(), err)
+		}
+
+		if err := history.AddChild("page1", child); !errors.Is(err, ErrBlockExists) {
+			t.Errorf("AddChild(%q) expected ErrBlockExists, got %v", child.ID(), err)
+		}
+	}
+
+	if err := history.AddChild("page1", nil); !errors.Is(err, ErrNilBlock) {
+		t.Errorf("expected ErrNilBlock, got %v", err)
+	}
+
+	if got := mustToJson(t, tree); got != original || history.CanUndo() {
+		t.Errorf("rejected inserts changed the tree: %s, want %s", got, original)
+	}
+}
+
+func TestHistory_UndoRemovesTheInsertedBlock(t *testing.T) {
+	first := NewBlockBuilder().WithID("x").WithType("paragraph").Build()
+	root := NewBlockBuilder().WithID("root").WithType("page").WithChildren([]BlockInterface{first}).Build()
+	history := NewHistory(root, 0)
+
+	if err := history.InsertChildAt("root", 0, NewBlockBuilder().WithID("y").WithType("heading").Build()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a sibling with the ID of the inserted block, added directly
+	duplicate := NewBlockBuilder().WithID("y").WithType("paragraph").Build()
+	root.SetChildren(slices.Insert(slices.Clone(root.Children()), 0, duplicate))
+
+	if err := history.Undo(); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if len(root.Children()) != 3 || root.Children()[0] != duplicate {
+		t.Error("a failed undo must not remove a sibling with the same ID")
+	}
+
+	root.SetChildren(root.Children()[1:])
+
+	if err := history.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(root.Children()) != 1 || root.Children()[0] != first {
+		t.Errorf("undo must remove the inserted block, got %v", root.Children())
+	}
+}
+
+func TestHistory_Limit(t *testing.T) {
+	tree := newTestTree()
+	history := NewHistory(tree, 2)
+
+	for _, title := range []string{"1", "2", "3"} {
+		if err := history.SetParameter("document1", "title", title); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	undone := []string{}
+
+	for history.CanUndo() {
+		if err := history.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		undone = append(undone, tree.Parameter("title"))
+	}
+
+	if !reflect.DeepEqual(undone, []string{"2", "1"}) {
+		t.Errorf("undone titles = %v, want [2 1]", undone)
+	}
+}
+
+func TestHistory_Errors(t *testing.T) {
+	history := NewHistory(newTestTree(), 0)
+
+	if err := history.SetParameter("missing", "a", "b"); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if err := history.RemoveBlock("document1"); !errors.Is(err, ErrRootBlock) {
+		t.Errorf("expected ErrRootBlock, got %v", err)
+	}
+
+	if err := history.InsertChildAt("page1", 10, NewBlock()); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+
+	if history.CanUndo() {
+		t.Error("failed edits must not be recorded")
+	}
+}
@@ -0,0 +1,365 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ImmutableBlock is a block which can not be changed once created
+//
+// The With* methods return a new block, which shares the unchanged
+// parameters and children with the original. Changing a descendant with
+// UpdateByID copies only the blocks on the path to it, all other subtrees
+// are shared. Because a given *ImmutableBlock always describes the same
+// tree, values derived from it (i.e. the rendered HTML) can be cached
+// by pointer, and keeping old versions costs almost nothing
+//
+// Example:
+//
+//	page := ui.ToImmutable(root)
+//	next, err := page.UpdateByID("image1", func(image *ui.ImmutableBlock) *ui.ImmutableBlock {
+//		return image.WithParameter("src", "b.png")
+//	})
+//	block := next.ToBlock()
+type ImmutableBlock struct {
+	id         string
+	blockType  string
+	content    string
+	parameters map[string]string
+	children   []*ImmutableBlock
+}
+
+// NewImmutableBlock creates an immutable block without parameters and children
+func NewImmutableBlock(id string, blockType string) *ImmutableBlock {
+	return &ImmutableBlock{id: id, blockType: blockType}
+}
+
+// ToImmutable returns an immutable copy of the block and its descendants
+//
+// Only the data of the blocks is copied (ID, type, content, parameters
+// and children), whatever the implementation of the blocks is
+func ToImmutable(block BlockInterface) *ImmutableBlock {
+	return toImmutableFrom(block, nil)
+}
+
+// toImmutableFrom returns an immutable copy of the block and its descendants,
+// reusing the blocks of previous (a former version of the tree, or nil)
+// which are equal to their copy, so that unchanged subtrees are shared
+func toImmutableFrom(block BlockInterface, previous *ImmutableBlock) *ImmutableBlock {
+	if block == nil {
+		return nil
+	}
+
+	previousChildren := map[string]*ImmutableBlock{}
+
+	if previous != nil {
+		for _, child := range previous.children {
+			previousChildren[child.id] = child
+		}
+	}
+
+	var children []*ImmutableBlock
+
+	for _, child := range block.Children() {
+		if child == nil {
+			continue
+		}
+
+		children = append(children, toImmutableFrom(child, previousChildren[child.ID()]))
+	}
+
+	parameters := block.Parameters()
+
+	if previous != nil &&
+		previous.id == block.ID() &&
+		previous.blockType == block.Type() &&
+		previous.content == block.Content() &&
+		maps.Equal(previous.parameters, parameters) &&
+		slices.Equal(previous.children, children) {
+		return previous
+	}
+
+	return &ImmutableBlock{
+		id:         block.ID(),
+		blockType:  block.Type(),
+		content:    block.Content(),
+		parameters: maps.Clone(parameters),
+		children:   children,
+	}
+}
+
+// ToBlock returns a mutable copy of the block and its descendants
+func (b *ImmutableBlock) ToBlock() BlockInterface {
+	if b == nil {
+		return nil
+	}
+
+	children := make([]BlockInterface, 0, len(b.children))
+
+	for _, child := range b.children {
+		children = append(children, child.ToBlock())
+	}
+
+	parameters := maps.Clone(b.parameters)
+
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+
+	block := NewBlock()
+	block.SetID(b.id)
+	block.SetType(b.blockType)
+	block.SetContent(b.content)
+	block.SetParameters(parameters)
+	block.SetChildren(children)
+	return block
+}
+
+// ToJson returns the JSON of the block and its descendants
+func (b *ImmutableBlock) ToJson() (string, error) {
+	return b.ToBlock().ToJson()
+}
+
+// == GETTERS =================================================================
+
+// ID returns the ID of the block
+func (b *ImmutableBlock) ID() string {
+	return b.id
+}
+
+// Type returns the type of the block
+func (b *ImmutableBlock) Type() string {
+	return b.blockType
+}
+
+// Content returns the content of the block
+func (b *ImmutableBlock) Content() string {
+	return b.content
+}
+
+// Parameter returns the value of the parameter, or the default
+// of the registered schema, if the parameter is not set
+func (b *ImmutableBlock) Parameter(key string) string {
+	return resolveParameter(b.parameters, b.blockType, key)
+}
+
+// HasParameter returns true if the parameter is set
+func (b *ImmutableBlock) HasParameter(key string) bool {
+	_, ok := b.parameters[key]
+	return ok
+}
+
+// Parameters returns a copy of the parameters
+func (b *ImmutableBlock) Parameters() map[string]string {
+	parameters := maps.Clone(b.parameters)
+
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+
+	return parameters
+}
+
+// Children returns a copy of the list of children,
+// the children themselves are shared
+func (b *ImmutableBlock) Children() []*ImmutableBlock {
+	return slices.Clone(b.children)
+}
+
+// ChildCount returns the number of children
+func (b *ImmutableBlock) ChildCount() int {
+	return len(b.children)
+}
+
+// Child returns the child at the index, or nil if out of range
+func (b *ImmutableBlock) Child(index int) *ImmutableBlock {
+	if index < 0 || index >= len(b.children) {
+		return nil
+	}
+
+	return b.children[index]
+}
+
+// FindByID returns the block or descendant with the ID, or nil if not found
+func (b *ImmutableBlock) FindByID(id string) *ImmutableBlock {
+	if b == nil {
+		return nil
+	}
+
+	if b.id == id {
+		return b
+	}
+
+	for _, child := range b.children {
+		if found := child.FindByID(id); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// == WITHERS =================================================================
+
+// WithID returns a copy of the block with the ID
+func (b *ImmutableBlock) WithID(id string) *ImmutableBlock {
+	clone := *b
+	clone.id = id
+	return &clone
+}
+
+// WithType returns a copy of the block with the type
+func (b *ImmutableBlock) WithType(blockType string) *ImmutableBlock {
+	clone := *b
+	clone.blockType = blockType
+	return &clone
+}
+
+// WithContent returns a copy of the block with the content
+func (b *ImmutableBlock) WithContent(content string) *ImmutableBlock {
+	clone := *b
+	clone.content = content
+	return &clone
+}
+
+// WithParameter returns a copy of the block with the parameter set
+func (b *ImmutableBlock) WithParameter(key string, value string) *ImmutableBlock {
+	clone := *b
+	clone.parameters = maps.Clone(b.parameters)
+
+	if clone.parameters == nil {
+		clone.parameters = map[string]string{}
+	}
+
+	clone.parameters[key] = value
+	return &clone
+}
+
+// WithoutParameter returns a copy of the block with the parameter removed
+func (b *ImmutableBlock) WithoutParameter(key string) *ImmutableBlock {
+	if !b.HasParameter(key) {
+		return b
+	}
+
+	clone := *b
+	clone.parameters = maps.Clone(b.parameters)
+	delete(clone.parameters, key)
+	return &clone
+}
+
+// WithParameters returns a copy of the block with the parameters replaced
+func (b *ImmutableBlock) WithParameters(parameters map[string]string) *ImmutableBlock {
+	clone := *b
+	clone.parameters = maps.Clone(parameters)
+	return &clone
+}
+
+// WithChild returns a copy of the block with the child appended,
+// a nil child is skipped and the block is returned unchanged
+func (b *ImmutableBlock) WithChild(child *ImmutableBlock) *ImmutableBlock {
+	if child == nil {
+		return b
+	}
+
+	clone := *b
+	clone.children = append(slices.Clone(b.children), child)
+	return &clone
+}
+
+// WithChildAt returns a copy of the block with the child inserted at the index
+func (b *ImmutableBlock) WithChildAt(index int, child *ImmutableBlock) (*ImmutableBlock, error) {
+	if child == nil {
+		return nil, fmt.Errorf("%w: child of %q", ErrNilBlock, b.id)
+	}
+
+	if index < 0 || index > len(b.children) {
+		return nil, fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+	}
+
+	clone := *b
+	clone.children = slices.Insert(slices.Clone(b.children), index, child)
+	return &clone, nil
+}
+
+// WithoutChild returns a copy of the block with the direct child with the ID removed
+func (b *ImmutableBlock) WithoutChild(id string) (*ImmutableBlock, error) {
+	index := slices.IndexFunc(b.children, func(child *ImmutableBlock) bool {
+		return child.id == id
+	})
+
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	clone := *b
+	clone.children = slices.Delete(slices.Clone(b.children), index, index+1)
+	return &clone, nil
+}
+
+// WithChildren returns a copy of the block with the children replaced,
+// nil children are skipped
+func (b *ImmutableBlock) WithChildren(children []*ImmutableBlock) *ImmutableBlock {
+	clone := *b
+	clone.children = slices.DeleteFunc(slices.Clone(children), func(child *ImmutableBlock) bool {
+		return child == nil
+	})
+	return &clone
+}
+
+// UpdateByID returns a copy of the tree in which the block with the ID
+// is replaced by the result of fn. Only the ancestors of the block
+// are copied, all other subtrees are shared with the original
+//
+// If fn returns nil the block is removed from its parent
+// (the root block can not be removed)
+func (b *ImmutableBlock) UpdateByID(id string, fn func(block *ImmutableBlock) *ImmutableBlock) (*ImmutableBlock, error) {
+	if b.id == id {
+		updated := fn(b)
+
+		if updated == nil {
+			return nil, ErrRootBlock
+		}
+
+		return updated, nil
+	}
+
+	updated, found := b.updateDescendant(id, fn)
+
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	return updated, nil
+}
+
+// updateDescendant copies the path to the descendant with the ID
+func (b *ImmutableBlock) updateDescendant(id string, fn func(block *ImmutableBlock) *ImmutableBlock) (*ImmutableBlock, bool) {
+	for index, child := range b.children {
+		var updated *ImmutableBlock
+
+		if child.id == id {
+			updated = fn(child)
+		} else {
+			var found bool
+			updated, found = child.updateDescendant(id, fn)
+
+			if !found {
+				continue
+			}
+		}
+
+		clone := *b
+		clone.children = slices.Clone(b.children)
+
+		if updated == nil {
+			clone.children = slices.Delete(clone.children, index, index+1)
+		} else {
+			clone.children[index] = updated
+		}
+
+		return &clone, true
+	}
+
+	return nil, false
+}
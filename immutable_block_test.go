@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestToImmutable(t *testing.T) {
+	tree := newTestTree()
+	tree.SetParameter("title", "Home")
+
+	immutable := ToImmutable(tree)
+
+	tree.SetParameter("title", "Changed")
+	FindByID(tree, "page1").SetType("changed")
+
+	if immutable.Parameter("title") != "Home" || immutable.FindByID("page1").Type() != "page" {
+		t.Error("changes of the original tree must not affect the immutable copy")
+	}
+
+	tree = newTestTree()
+	tree.SetParameter("title", "Home")
+	FindByID(tree, "paragraph1").SetContent("Hello")
+
+	if got, want := mustToJson(t, ToImmutable(tree).ToBlock()), mustToJson(t, tree); got != want {
+		t.Errorf("ToBlock() = %s, want %s", got, want)
+	}
+
+	if ToImmutable(nil) != nil {
+		t.Error("ToImmutable(nil) must be nil")
+	}
+}
+
+func TestImmutableBlock_With(t *testing.T) {
+	page := NewImmutableBlock("page1", "page").
+		WithChild(NewImmutableBlock("paragraph1", "paragraph")).
+		WithParameter("title", "Home")
+
+	updated := page.WithType("article").WithParameter("title", "About").WithoutParameter("missing").WithContent("Text")
+
+	if page.Type() != "page" || page.Parameter("title") != "Home" || page.Content() != "" {
+		t.Errorf("original changed: %s %v", page.Type(), page.Parameters())
+	}
+
+	if updated.Type() != "article" || updated.Parameter("title") != "About" || updated.Content() != "Text" {
+		t.Errorf("unexpected updated block: %s %v", updated.Type(), updated.Parameters())
+	}
+
+	if updated.Child(0) != page.Child(0) {
+		t.Error("unchanged children must be shared")
+	}
+
+	withChild, err := page.WithChildAt(0, NewImmutableBlock("heading1", "heading"))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if withChild.ChildCount() != 2 || withChild.Child(0).ID() != "heading1" || page.ChildCount() != 1 {
+		t.Errorf("unexpected children %d, original %d", withChild.ChildCount(), page.ChildCount())
+	}
+
+	withoutChild, err := withChild.WithoutChild("paragraph1")
+
+	if err != nil || withoutChild.ChildCount() != 1 || withChild.ChildCount() != 2 {
+		t.Errorf("unexpected WithoutChild result: %v", err)
+	}
+
+	if _, err := page.WithChildAt(5, NewImmutableBlock("x", "x")); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+
+	if _, err := page.WithoutChild("missing"); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if _, err := page.WithChildAt(0, nil); !errors.Is(err, ErrNilBlock) {
+		t.Errorf("expected ErrNilBlock, got %v", err)
+	}
+
+	if got := page.WithChild(nil); got != page || got.ChildCount() != 1 {
+		t.Error("WithChild(nil) must return the block unchanged")
+	}
+}
+
+func TestImmutableBlock_UpdateByID(t *testing.T) {
+	tree := ToImmutable(newTestTree())
+
+	updated, err := tree.UpdateByID("image1", func(image *ImmutableBlock) *ImmutableBlock {
+		return image.WithParameter("src", "b.png")
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if updated.FindByID("image1").Parameter("src") != "b.png" || tree.FindByID("image1").HasParameter("src") {
+		t.Error("unexpected image parameters")
+	}
+
+	if updated == tree || updated.Child(0) == tree.Child(0) {
+		t.Error("the ancestors of the updated block must be copied")
+	}
+
+	if updated.Child(1) != tree.Child(1) || updated.FindByID("paragraph1") != tree.FindByID("paragraph1") {
+		t.Error("the other subtrees must be shared")
+	}
+
+	removed, err := tree.UpdateByID("paragraph2", func(*ImmutableBlock) *ImmutableBlock { return nil })
+
+	if err != nil || removed.FindByID("paragraph2") != nil || tree.FindByID("paragraph2") == nil {
+		t.Errorf("unexpected remove result: %v", err)
+	}
+
+	if _, err := tree.UpdateByID("missing", func(b *ImmutableBlock) *ImmutableBlock { return b }); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if _, err := tree.UpdateByID("document1", func(*ImmutableBlock) *ImmutableBlock { return nil }); !errors.Is(err, ErrRootBlock) {
+		t.Errorf("expected ErrRootBlock, got %v", err)
+	}
+}
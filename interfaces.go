@@ -3,6 +3,7 @@ package ui
 type BlockInterface interface {
 	IDInterface
 	ChildrenInterface
+	ContentInterface
 	ParametersInterface
 	TypeInterface
 
@@ -18,6 +19,15 @@ type ChildrenInterface interface {
 	SetChildren([]BlockInterface)
 	AddChild(BlockInterface)
 	AddChildren([]BlockInterface)
+	InsertChildAt(index int, child BlockInterface) error
+	RemoveChild(id string) error
+}
+
+// ContentInterface is the text payload of a block (i.e. the text of
+// a paragraph), kept apart from its configuration parameters
+type ContentInterface interface {
+	Content() string
+	SetContent(string)
 }
 
 type IDInterface interface {
@@ -56,7 +66,7 @@ type ToJsonPrettyInterface interface {
 }
 
 type ToJsonObjectInterface interface {
-	ToJsonObject() blockJsonObject
+	ToJsonObject() BlockJsonObject
 }
 
 type ToMapInterface interface {
@@ -100,6 +110,7 @@ func (b *parametersImplementation) SetParameter(key, value string) {
 type BlockBuilderInterface interface {
 	WithID(string) BlockBuilderInterface
 	WithType(string) BlockBuilderInterface
+	WithContent(string) BlockBuilderInterface
 	WithParameters(map[string]string) BlockBuilderInterface
 	WithChildren([]BlockInterface) BlockBuilderInterface
 	Build() BlockInterface
@@ -108,6 +119,7 @@ type BlockBuilderInterface interface {
 type blockBuilder struct {
 	id         string
 	blockType  string
+	content    string
 	parameters map[string]string
 	children   []BlockInterface
 }
@@ -128,6 +140,11 @@ func (b *blockBuilder) WithType(blockType string) BlockBuilderInterface {
 	return b
 }
 
+func (b *blockBuilder) WithContent(content string) BlockBuilderInterface {
+	b.content = content
+	return b
+}
+
 func (b *blockBuilder) WithParameters(parameters map[string]string) BlockBuilderInterface {
 	b.parameters = parameters
 	return b
@@ -150,6 +167,7 @@ func (b *blockBuilder) Build() BlockInterface {
 	block := NewBlock()
 	block.SetID(b.id)
 	block.SetType(b.blockType)
+	block.SetContent(b.content)
 	block.SetParameters(b.parameters)
 	block.SetChildren(b.children)
 	return block
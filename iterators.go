@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"iter"
+	"slices"
+)
+
+// Descendants returns an iterator over the descendants of the block
+// in pre-order, the block itself is not included
+//
+// Example:
+//
+//	for block := range ui.Descendants(document) {
+//		...
+//	}
+func Descendants(block BlockInterface) iter.Seq[BlockInterface] {
+	return func(yield func(BlockInterface) bool) {
+		if block == nil {
+			return
+		}
+
+		for _, child := range block.Children() {
+			if !yieldPreOrder(child, 0, func(_ int, b BlockInterface) bool { return yield(b) }) {
+				return
+			}
+		}
+	}
+}
+
+// Ancestors returns an iterator over the ancestors of the block with the ID
+// in the tree, from its parent up to the root. Nothing is yielded
+// if the block is not found
+func Ancestors(root BlockInterface, id string) iter.Seq[BlockInterface] {
+	return func(yield func(BlockInterface) bool) {
+		path := FindPath(root, id)
+
+		if len(path) < 2 {
+			return
+		}
+
+		for _, ancestor := range slices.Backward(path[:len(path)-1]) {
+			if !yield(ancestor) {
+				return
+			}
+		}
+	}
+}
+
+// ChildrenOf returns an iterator over the direct children of the block
+func ChildrenOf(block BlockInterface) iter.Seq[BlockInterface] {
+	return func(yield func(BlockInterface) bool) {
+		if block == nil {
+			return
+		}
+
+		for _, child := range block.Children() {
+			if child == nil {
+				continue
+			}
+
+			if !yield(child) {
+				return
+			}
+		}
+	}
+}
+
+// BlocksOfType returns an iterator over the blocks of the type in the tree,
+// in pre-order, the root block included
+func BlocksOfType(root BlockInterface, blockType string) iter.Seq[BlockInterface] {
+	return func(yield func(BlockInterface) bool) {
+		yieldPreOrder(root, 0, func(_ int, block BlockInterface) bool {
+			if block.Type() != blockType {
+				return true
+			}
+			return yield(block)
+		})
+	}
+}
+
+// WithDepth returns an iterator over the block and its descendants
+// in pre-order, together with their depth (the depth of the root is 0)
+//
+// Example:
+//
+//	for depth, block := range ui.WithDepth(document) {
+//		fmt.Println(strings.Repeat("  ", depth) + block.ID())
+//	}
+func WithDepth(root BlockInterface) iter.Seq2[int, BlockInterface] {
+	return func(yield func(int, BlockInterface) bool) {
+		yieldPreOrder(root, 0, yield)
+	}
+}
+
+// yieldPreOrder yields the block and its descendants in pre-order,
+// returns false if the iteration was stopped
+func yieldPreOrder(block BlockInterface, depth int, yield func(int, BlockInterface) bool) bool {
+	if block == nil {
+		return true
+	}
+
+	if !yield(depth, block) {
+		return false
+	}
+
+	for _, child := range block.Children() {
+		if !yieldPreOrder(child, depth+1, yield) {
+			return false
+		}
+	}
+
+	return true
+}
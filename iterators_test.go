@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescendants(t *testing.T) {
+	got := []string{}
+
+	for block := range Descendants(newTestTree()) {
+		got = append(got, block.ID())
+	}
+
+	want := []string{"page1", "paragraph1", "image1", "page2", "image2", "paragraph2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Descendants() = %v, want %v", got, want)
+	}
+}
+
+func TestDescendants_Break(t *testing.T) {
+	got := []string{}
+
+	for block := range Descendants(newTestTree()) {
+		got = append(got, block.ID())
+		if block.ID() == "image1" {
+			break
+		}
+	}
+
+	want := []string{"page1", "paragraph1", "image1"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Descendants() = %v, want %v", got, want)
+	}
+
+	for range Descendants(nil) {
+		t.Error("expected no descendants for nil block")
+	}
+}
+
+func TestAncestors(t *testing.T) {
+	got := []string{}
+
+	for block := range Ancestors(newTestTree(), "paragraph2") {
+		got = append(got, block.ID())
+	}
+
+	want := []string{"page2", "document1"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Ancestors(paragraph2) = %v, want %v", got, want)
+	}
+
+	for range Ancestors(newTestTree(), "document1") {
+		t.Error("expected no ancestors for the root")
+	}
+
+	for range Ancestors(newTestTree(), "missing") {
+		t.Error("expected no ancestors for a missing block")
+	}
+}
+
+func TestChildrenOf(t *testing.T) {
+	got := []string{}
+
+	for block := range ChildrenOf(newTestTree()) {
+		got = append(got, block.ID())
+	}
+
+	want := []string{"page1", "page2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChildrenOf() = %v, want %v", got, want)
+	}
+}
+
+func TestBlocksOfType(t *testing.T) {
+	got := []string{}
+
+	for block := range BlocksOfType(newTestTree(), "paragraph") {
+		got = append(got, block.ID())
+	}
+
+	want := []string{"paragraph1", "paragraph2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BlocksOfType(paragraph) = %v, want %v", got, want)
+	}
+
+	for block := range BlocksOfType(newTestTree(), "image") {
+		if block.ID() != "image1" {
+			t.Errorf("expected image1 first, got %s", block.ID())
+		}
+		break
+	}
+}
+
+func TestWithDepth(t *testing.T) {
+	ids := []string{}
+	depths := []int{}
+
+	for depth, block := range WithDepth(newTestTree()) {
+		ids = append(ids, block.ID())
+		depths = append(depths, depth)
+		if block.ID() == "page2" {
+			break
+		}
+	}
+
+	wantIDs := []string{"document1", "page1", "paragraph1", "image1", "page2"}
+	wantDepths := []int{0, 1, 2, 2, 1}
+
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Errorf("WithDepth() ids = %v, want %v", ids, wantIDs)
+	}
+
+	if !reflect.DeepEqual(depths, wantDepths) {
+		t.Errorf("WithDepth() depths = %v, want %v", depths, wantDepths)
+	}
+}
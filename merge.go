@@ -0,0 +1,598 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrMergeConflict is returned by MergeResult.Block,
+// when there are unresolved conflicts
+var ErrMergeConflict = errors.New("merge conflict")
+
+// ConflictKind is the kind of a merge conflict
+type ConflictKind string
+
+const (
+	// ConflictParameter is a parameter changed differently on both sides
+	// (modified to different values, or modified on one side and removed
+	// on the other)
+	ConflictParameter ConflictKind = "parameter"
+
+	// ConflictType is a block type changed differently on both sides
+	ConflictType ConflictKind = "type"
+
+	// ConflictContent is a block content changed differently on both sides
+	ConflictContent ConflictKind = "content"
+
+	// ConflictDeleteModify is a block deleted on one side, and edited on
+	// the other side (changed, moved, or given new or moved-in children)
+	ConflictDeleteModify ConflictKind = "delete_modify"
+
+	// ConflictMove is a block moved to different parents on both sides,
+	// or moved on both sides in a way which would create a cycle
+	ConflictMove ConflictKind = "move"
+)
+
+// Resolution is the side chosen to resolve a conflict
+type Resolution int
+
+const (
+	// ResolutionOurs keeps the change of our side
+	ResolutionOurs Resolution = iota + 1
+
+	// ResolutionTheirs keeps the change of their side
+	ResolutionTheirs
+)
+
+// Conflict is a change made differently on both sides of a merge
+type Conflict struct {
+	// Kind is the kind of the conflict
+	Kind ConflictKind
+
+	// BlockID is the ID of the block
+	BlockID string
+
+	// Key is the parameter key, for ConflictParameter
+	Key string
+
+	// Base, Ours and Theirs are the values of each side: the parameter
+	// value for ConflictParameter, the type for ConflictType, the content
+	// for ConflictContent, and the parent ID for ConflictMove
+	Base   string
+	Ours   string
+	Theirs string
+
+	// OursDeleted and TheirsDeleted are true if the side removed
+	// the parameter (ConflictParameter) or deleted the block
+	// (ConflictDeleteModify)
+	OursDeleted   bool
+	TheirsDeleted bool
+
+	// Resolution is the chosen side, or 0 if the conflict is not resolved
+	Resolution Resolution
+}
+
+// MergeResult is the result of a three-way merge, see Merge
+type MergeResult struct {
+	merger      merger
+	resolutions map[string]Resolution
+}
+
+// Merge combines the changes made to the base tree on our side and
+// on their side, matching blocks by ID
+//
+// Changes made on one side only are applied: parameter, type and
+// position changes, added and deleted blocks. Changes made on both
+// sides are conflicts, which must be resolved before building the
+// merged tree. When both sides reorder the same children, our order
+// is kept
+//
+// Example:
+//
+//	result := ui.Merge(published, draft, live)
+//	for _, conflict := range result.Conflicts() {
+//		result.Resolve(conflict, ui.ResolutionOurs)
+//	}
+//	merged, err := result.Block()
+func Merge(base, ours, theirs BlockInterface) *MergeResult {
+	return &MergeResult{
+		merger:      newMerger(base, ours, theirs),
+		resolutions: map[string]Resolution{},
+	}
+}
+
+// Conflicts returns the conflicts of the merge, with their resolution
+//
+// Resolving a conflict can reveal other conflicts (i.e. keeping a block
+// deleted on one side, whose parent was deleted too) or make some moot
+func (r *MergeResult) Conflicts() []Conflict {
+	_, conflicts := r.merger.run(r.resolutions)
+	return conflicts
+}
+
+// Unresolved returns the conflicts which are not resolved yet
+func (r *MergeResult) Unresolved() []Conflict {
+	return slices.DeleteFunc(r.Conflicts(), func(conflict Conflict) bool {
+		return conflict.Resolution != 0
+	})
+}
+
+// HasConflicts returns true if there are unresolved conflicts
+func (r *MergeResult) HasConflicts() bool {
+	return len(r.Unresolved()) > 0
+}
+
+// Resolve resolves the conflict with the side
+func (r *MergeResult) Resolve(conflict Conflict, resolution Resolution) {
+	r.resolutions[conflict.key()] = resolution
+}
+
+// ResolveAll resolves all unresolved conflicts with the side,
+// including the conflicts revealed by the resolutions
+func (r *MergeResult) ResolveAll(resolution Resolution) {
+	for unresolved := r.Unresolved(); len(unresolved) > 0; unresolved = r.Unresolved() {
+		for _, conflict := range unresolved {
+			r.Resolve(conflict, resolution)
+		}
+	}
+}
+
+// Block builds the merged tree
+//
+// Returns ErrMergeConflict if there are unresolved conflicts,
+// and ErrCyclicMove if the resolutions make a block its own ancestor
+func (r *MergeResult) Block() (BlockInterface, error) {
+	nodes, conflicts := r.merger.run(r.resolutions)
+
+	unresolved := 0
+
+	for _, conflict := range conflicts {
+		if conflict.Resolution == 0 {
+			unresolved++
+		}
+	}
+
+	if unresolved > 0 {
+		return nil, fmt.Errorf("%w: %d unresolved", ErrMergeConflict, unresolved)
+	}
+
+	return r.merger.build(nodes)
+}
+
+func (c Conflict) key() string {
+	return string(c.Kind) + "\x00" + c.BlockID + "\x00" + c.Key
+}
+
+// == MERGER ==================================================================
+
+type merger struct {
+	base   diffIndex
+	ours   diffIndex
+	theirs diffIndex
+
+	// ids are the IDs of all blocks: ours, theirs then base, in pre-order
+	ids []string
+}
+
+type mergeNode struct {
+	id         string
+	blockType  string
+	content    string
+	parameters map[string]string
+	parentID   string
+
+	// moved is true if the parent was changed on one side
+	moved bool
+}
+
+func newMerger(base, ours, theirs BlockInterface) merger {
+	m := merger{
+		base:   newDiffIndex(base),
+		ours:   newDiffIndex(ours),
+		theirs: newDiffIndex(theirs),
+	}
+
+	seen := map[string]bool{}
+
+	for _, index := range []diffIndex{m.ours, m.theirs, m.base} {
+		for _, id := range index.order {
+			if !seen[id] {
+				seen[id] = true
+				m.ids = append(m.ids, id)
+			}
+		}
+	}
+
+	return m
+}
+
+// run merges the trees with the resolutions, unresolved conflicts
+// are resolved with our side. Returns the merged blocks keyed by ID,
+// and the conflicts found
+func (m merger) run(resolutions map[string]Resolution) (map[string]*mergeNode, []Conflict) {
+	nodes := map[string]*mergeNode{}
+	conflicts := []Conflict{}
+
+	resolve := func(conflict Conflict) Resolution {
+		conflict.Resolution = resolutions[conflict.key()]
+		conflicts = append(conflicts, conflict)
+
+		if conflict.Resolution == 0 {
+			return ResolutionOurs
+		}
+
+		return conflict.Resolution
+	}
+
+	// deleted are the blocks deleted on one side without conflict
+	deleted := map[string]bool{}
+
+	// revived are the blocks deleted on one side, kept on conflict
+	revived := []string{}
+
+	for _, id := range m.ids {
+		baseEntry, inBase := m.base.entries[id]
+		oursEntry, inOurs := m.ours.entries[id]
+		theirsEntry, inTheirs := m.theirs.entries[id]
+
+		switch {
+		case !inOurs && !inTheirs:
+			continue
+		case inBase && (!inOurs || !inTheirs):
+			kept := oursEntry
+
+			if !inOurs {
+				kept = theirsEntry
+			}
+
+			if !entryModified(baseEntry, kept) {
+				deleted[id] = true
+				continue
+			}
+
+			resolution := resolve(Conflict{Kind: ConflictDeleteModify, BlockID: id, OursDeleted: !inOurs, TheirsDeleted: !inTheirs})
+
+			if (resolution == ResolutionOurs) == inOurs {
+				nodes[id] = newMergeNode(kept)
+				revived = append(revived, id)
+			}
+		case !inOurs:
+			nodes[id] = newMergeNode(theirsEntry)
+		case !inTheirs:
+			nodes[id] = newMergeNode(oursEntry)
+		default:
+			nodes[id] = m.mergeEntries(id, baseEntry, inBase, oursEntry, theirsEntry, resolve)
+		}
+	}
+
+	// a kept block is kept with its descendants, which were deleted
+	// with it on the other side
+	reviveDescendants := func(id string) {
+		index := m.ours
+
+		if _, inOurs := index.entries[id]; !inOurs {
+			index = m.theirs
+		}
+
+		_ = Walk(index.entries[id].block, func(block BlockInterface, _ int) error {
+			if deleted[block.ID()] {
+				delete(deleted, block.ID())
+				nodes[block.ID()] = newMergeNode(index.entries[block.ID()])
+			}
+			return nil
+		})
+	}
+
+	for _, id := range revived {
+		reviveDescendants(id)
+	}
+
+	// revive the deleted parents of kept blocks, on conflict
+	for changed := true; changed; {
+		changed = false
+
+		for _, id := range m.ids {
+			node := nodes[id]
+
+			if node == nil || !deleted[node.parentID] {
+				continue
+			}
+
+			parentID := node.parentID
+			delete(deleted, parentID)
+			changed = true
+
+			oursEntry, inOurs := m.ours.entries[parentID]
+			theirsEntry := m.theirs.entries[parentID]
+
+			resolution := resolve(Conflict{Kind: ConflictDeleteModify, BlockID: parentID, OursDeleted: !inOurs, TheirsDeleted: inOurs})
+
+			if resolution == ResolutionOurs && inOurs {
+				nodes[parentID] = newMergeNode(oursEntry)
+				reviveDescendants(parentID)
+			} else if resolution == ResolutionTheirs && !inOurs {
+				nodes[parentID] = newMergeNode(theirsEntry)
+				reviveDescendants(parentID)
+			}
+		}
+	}
+
+	// moves on both sides creating a cycle
+	for _, id := range m.ids {
+		node := nodes[id]
+
+		if node == nil || !node.moved || !inMergeCycle(nodes, id) {
+			continue
+		}
+
+		baseEntry := m.base.entries[id]
+		oursParentID := m.ours.entries[id].parentID
+		theirsParentID := m.theirs.entries[id].parentID
+
+		resolution := resolve(Conflict{Kind: ConflictMove, BlockID: id, Base: baseEntry.parentID, Ours: oursParentID, Theirs: theirsParentID})
+
+		node.parentID = oursParentID
+
+		if resolution == ResolutionTheirs {
+			node.parentID = theirsParentID
+		}
+	}
+
+	return nodes, conflicts
+}
+
+// mergeEntries merges a block present on both sides
+func (m merger) mergeEntries(id string, baseEntry diffEntry, inBase bool, oursEntry, theirsEntry diffEntry, resolve func(Conflict) Resolution) *mergeNode {
+	baseType := ""
+	baseContent := ""
+	baseParameters := map[string]string{}
+
+	if inBase {
+		baseType = baseEntry.block.Type()
+		baseContent = baseEntry.block.Content()
+		baseParameters = baseEntry.block.Parameters()
+	}
+
+	node := &mergeNode{id: id, parameters: map[string]string{}}
+
+	var conflict bool
+
+	node.blockType, conflict = merge3(baseType, oursEntry.block.Type(), theirsEntry.block.Type())
+
+	if conflict && resolve(Conflict{Kind: ConflictType, BlockID: id, Base: baseType, Ours: oursEntry.block.Type(), Theirs: theirsEntry.block.Type()}) == ResolutionTheirs {
+		node.blockType = theirsEntry.block.Type()
+	}
+
+	node.content, conflict = merge3(baseContent, oursEntry.block.Content(), theirsEntry.block.Content())
+
+	if conflict && resolve(Conflict{Kind: ConflictContent, BlockID: id, Base: baseContent, Ours: oursEntry.block.Content(), Theirs: theirsEntry.block.Content()}) == ResolutionTheirs {
+		node.content = theirsEntry.block.Content()
+	}
+
+	oursParameters := oursEntry.block.Parameters()
+	theirsParameters := theirsEntry.block.Parameters()
+
+	keys := slices.Sorted(maps.Keys(baseParameters))
+	keys = append(keys, slices.Sorted(maps.Keys(oursParameters))...)
+	keys = append(keys, slices.Sorted(maps.Keys(theirsParameters))...)
+	slices.Sort(keys)
+
+	for _, key := range slices.Compact(keys) {
+		baseValue, inBaseParameters := baseParameters[key]
+		oursValue, inOursParameters := oursParameters[key]
+		theirsValue, inTheirsParameters := theirsParameters[key]
+
+		value, exists, conflict := mergeParameter(
+			baseValue, inBaseParameters,
+			oursValue, inOursParameters,
+			theirsValue, inTheirsParameters,
+		)
+
+		if conflict {
+			resolution := resolve(Conflict{
+				Kind:          ConflictParameter,
+				BlockID:       id,
+				Key:           key,
+				Base:          baseValue,
+				Ours:          oursValue,
+				Theirs:        theirsValue,
+				OursDeleted:   !inOursParameters,
+				TheirsDeleted: !inTheirsParameters,
+			})
+
+			value, exists = oursValue, inOursParameters
+
+			if resolution == ResolutionTheirs {
+				value, exists = theirsValue, inTheirsParameters
+			}
+		}
+
+		if exists {
+			node.parameters[key] = value
+		}
+	}
+
+	baseParentID := ""
+
+	if inBase {
+		baseParentID = baseEntry.parentID
+	}
+
+	node.parentID, conflict = merge3(baseParentID, oursEntry.parentID, theirsEntry.parentID)
+	node.moved = !conflict && node.parentID != baseParentID
+
+	if conflict && resolve(Conflict{Kind: ConflictMove, BlockID: id, Base: baseParentID, Ours: oursEntry.parentID, Theirs: theirsEntry.parentID}) == ResolutionTheirs {
+		node.parentID = theirsEntry.parentID
+	}
+
+	return node
+}
+
+// build builds the merged tree from the merged blocks. Blocks whose
+// parent is not merged (i.e. deleted by a resolution) are dropped
+func (m merger) build(nodes map[string]*mergeNode) (BlockInterface, error) {
+	for _, id := range m.ids {
+		if nodes[id] != nil && inMergeCycle(nodes, id) {
+			return nil, fmt.Errorf("%w: %q", ErrCyclicMove, id)
+		}
+	}
+
+	childrenOf := map[string][]string{}
+
+	for _, id := range m.ids {
+		if node := nodes[id]; node != nil {
+			childrenOf[node.parentID] = append(childrenOf[node.parentID], id)
+		}
+	}
+
+	roots := childrenOf[""]
+
+	if len(roots) != 1 {
+		return nil, fmt.Errorf("%w: merged tree has %d root blocks", ErrMergeConflict, len(roots))
+	}
+
+	var buildNode func(id string) BlockInterface
+
+	buildNode = func(id string) BlockInterface {
+		node := nodes[id]
+
+		children := []BlockInterface{}
+
+		for _, childID := range m.orderChildren(id, childrenOf[id]) {
+			children = append(children, buildNode(childID))
+		}
+
+		block := NewBlock()
+		block.SetID(node.id)
+		block.SetType(node.blockType)
+		block.SetContent(node.content)
+		block.SetParameters(node.parameters)
+		block.SetChildren(children)
+		return block
+	}
+
+	return buildNode(roots[0]), nil
+}
+
+// orderChildren orders the merged children of a parent: their order
+// is used if only they reordered the children, otherwise our order.
+// Children missing from the chosen order are placed after their
+// preceding sibling in the other order
+func (m merger) orderChildren(parentID string, childIDs []string) []string {
+	isChild := func(id string) bool {
+		return slices.Contains(childIDs, id)
+	}
+
+	filter := func(ids []string, keep func(string) bool) []string {
+		return slices.DeleteFunc(slices.Clone(ids), func(id string) bool { return !keep(id) })
+	}
+
+	baseOrder := filter(m.base.childIDs[parentID], isChild)
+	oursOrder := filter(m.ours.childIDs[parentID], isChild)
+	theirsOrder := filter(m.theirs.childIDs[parentID], isChild)
+
+	reordered := func(order []string) bool {
+		inOrder := func(id string) bool { return slices.Contains(order, id) }
+		inBase := func(id string) bool { return slices.Contains(baseOrder, id) }
+		return !slices.Equal(filter(order, inBase), filter(baseOrder, inOrder))
+	}
+
+	primary, secondary := oursOrder, theirsOrder
+
+	if !reordered(oursOrder) && reordered(theirsOrder) {
+		primary, secondary = theirsOrder, oursOrder
+	}
+
+	ordered := slices.Clone(primary)
+
+	for _, others := range [][]string{secondary, baseOrder, childIDs} {
+		for i, id := range others {
+			if slices.Contains(ordered, id) {
+				continue
+			}
+
+			position := 0
+
+			for j := i - 1; j >= 0; j-- {
+				if index := slices.Index(ordered, others[j]); index >= 0 {
+					position = index + 1
+					break
+				}
+			}
+
+			ordered = slices.Insert(ordered, position, id)
+		}
+	}
+
+	return ordered
+}
+
+func newMergeNode(entry diffEntry) *mergeNode {
+	parameters := maps.Clone(entry.block.Parameters())
+
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+
+	return &mergeNode{
+		id:         entry.block.ID(),
+		blockType:  entry.block.Type(),
+		content:    entry.block.Content(),
+		parameters: parameters,
+		parentID:   entry.parentID,
+	}
+}
+
+// entryModified returns true if the block was changed, or moved
+func entryModified(baseEntry, entry diffEntry) bool {
+	return baseEntry.parentID != entry.parentID ||
+		baseEntry.block.Type() != entry.block.Type() ||
+		baseEntry.block.Content() != entry.block.Content() ||
+		!maps.Equal(baseEntry.block.Parameters(), entry.block.Parameters())
+}
+
+// merge3 returns the value changed on one side, and true if both sides
+// changed it to different values (in which case ours is returned)
+func merge3(base, ours, theirs string) (string, bool) {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours, false
+	case ours == base:
+		return theirs, false
+	}
+
+	return ours, true
+}
+
+// mergeParameter is merge3 for a parameter, which may not exist
+func mergeParameter(base string, inBase bool, ours string, inOurs bool, theirs string, inTheirs bool) (string, bool, bool) {
+	equal := func(a string, inA bool, b string, inB bool) bool {
+		return inA == inB && (!inA || a == b)
+	}
+
+	switch {
+	case equal(ours, inOurs, theirs, inTheirs), equal(theirs, inTheirs, base, inBase):
+		return ours, inOurs, false
+	case equal(ours, inOurs, base, inBase):
+		return theirs, inTheirs, false
+	}
+
+	return ours, inOurs, true
+}
+
+// inMergeCycle returns true if the block is its own ancestor
+func inMergeCycle(nodes map[string]*mergeNode, id string) bool {
+	current := nodes[id]
+
+	for steps := 0; current != nil && steps < len(nodes); steps++ {
+		current = nodes[current.parentID]
+
+		if current != nil && current.id == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,301 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func mustMerge(t *testing.T, result *MergeResult) BlockInterface {
+	t.Helper()
+
+	merged, err := result.Block()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return merged
+}
+
+func TestMerge(t *testing.T) {
+	base := newTestTree()
+
+	ours := newTestTree()
+	FindByID(ours, "image1").SetParameter("src", "a.png")
+	FindByID(ours, "page1").AddChild(NewBlockBuilder().WithID("heading1").WithType("heading").Build())
+	if err := MoveBlock(ours, "paragraph2", "page1", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	theirs := newTestTree()
+	theirs.SetParameter("title", "Home")
+	FindByID(theirs, "image1").SetType("video")
+	if err := FindByID(theirs, "page2").RemoveChild("image2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveBlock(theirs, "image1", "page1", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	result := Merge(base, ours, theirs)
+
+	if result.HasConflicts() {
+		t.Fatalf("unexpected conflicts %+v", result.Conflicts())
+	}
+
+	merged := mustMerge(t, result)
+
+	if merged.Parameter("title") != "Home" {
+		t.Errorf("unexpected root parameters %v", merged.Parameters())
+	}
+
+	image := FindByID(merged, "image1")
+
+	if image.Type() != "video" || image.Parameter("src") != "a.png" {
+		t.Errorf("unexpected image1 %v", image.ToMap())
+	}
+
+	if FindByID(merged, "image2") != nil {
+		t.Error("image2 should be deleted")
+	}
+
+	if got := childIDs(FindByID(merged, "page1")); !reflect.DeepEqual(got, []string{"paragraph2", "image1", "heading1", "paragraph1"}) {
+		t.Errorf("children of page1 = %v", got)
+	}
+
+	if got := childIDs(FindByID(merged, "page2")); len(got) != 0 {
+		t.Errorf("children of page2 = %v", got)
+	}
+}
+
+func TestMerge_ParameterConflicts(t *testing.T) {
+	base := newTestTree()
+	FindByID(base, "image1").SetParameter("alt", "Image")
+
+	ours := newTestTree()
+	FindByID(ours, "image1").SetParameters(map[string]string{"src": "ours.png", "alt": "Ours"})
+
+	theirs := newTestTree()
+	FindByID(theirs, "image1").SetParameters(map[string]string{"src": "theirs.png"})
+
+	result := Merge(base, ours, theirs)
+
+	want := []Conflict{
+		{Kind: ConflictParameter, BlockID: "image1", Key: "alt", Base: "Image", Ours: "Ours", TheirsDeleted: true},
+		{Kind: ConflictParameter, BlockID: "image1", Key: "src", Ours: "ours.png", Theirs: "theirs.png"},
+	}
+
+	if got := result.Conflicts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Conflicts() = %+v, want %+v", got, want)
+	}
+
+	if _, err := result.Block(); !errors.Is(err, ErrMergeConflict) {
+		t.Errorf("expected ErrMergeConflict, got %v", err)
+	}
+
+	result.Resolve(want[0], ResolutionTheirs)
+
+	if got := len(result.Unresolved()); got != 1 {
+		t.Errorf("Unresolved() = %d, want 1", got)
+	}
+
+	result.ResolveAll(ResolutionOurs)
+
+	merged := mustMerge(t, result)
+
+	if got := FindByID(merged, "image1").Parameters(); !reflect.DeepEqual(got, map[string]string{"src": "ours.png"}) {
+		t.Errorf("Parameters() = %v", got)
+	}
+
+	if result.Conflicts()[0].Resolution != ResolutionTheirs {
+		t.Errorf("unexpected resolution %+v", result.Conflicts()[0])
+	}
+}
+
+func TestMerge_Content(t *testing.T) {
+	ours := newTestTree()
+	FindByID(ours, "paragraph1").SetContent("Ours")
+	FindByID(ours, "paragraph2").SetContent("Only ours")
+
+	theirs := newTestTree()
+	FindByID(theirs, "paragraph1").SetContent("Theirs")
+
+	result := Merge(newTestTree(), ours, theirs)
+
+	want := []Conflict{{Kind: ConflictContent, BlockID: "paragraph1", Ours: "Ours", Theirs: "Theirs"}}
+
+	if got := result.Conflicts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Conflicts() = %+v, want %+v", got, want)
+	}
+
+	result.ResolveAll(ResolutionTheirs)
+
+	merged := mustMerge(t, result)
+
+	if FindByID(merged, "paragraph1").Content() != "Theirs" || FindByID(merged, "paragraph2").Content() != "Only ours" {
+		t.Errorf("unexpected merged tree %s", mustToJson(t, merged))
+	}
+}
+
+func TestMerge_TypeAndMoveConflicts(t *testing.T) {
+	ours := newTestTree()
+	FindByID(ours, "paragraph1").SetType("heading")
+	_ = MoveBlock(ours, "image1", "page2", 0)
+
+	theirs := newTestTree()
+	FindByID(theirs, "paragraph1").SetType("quote")
+	_ = MoveBlock(theirs, "image1", "document1", 0)
+
+	result := Merge(newTestTree(), ours, theirs)
+
+	want := []Conflict{
+		{Kind: ConflictType, BlockID: "paragraph1", Base: "paragraph", Ours: "heading", Theirs: "quote"},
+		{Kind: ConflictMove, BlockID: "image1", Base: "page1", Ours: "page2", Theirs: "document1"},
+	}
+
+	if got := result.Conflicts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Conflicts() = %+v, want %+v", got, want)
+	}
+
+	result.ResolveAll(ResolutionTheirs)
+
+	merged := mustMerge(t, result)
+
+	if FindByID(merged, "paragraph1").Type() != "quote" || FindParent(merged, "image1").ID() != "document1" {
+		t.Errorf("unexpected merged tree %s", mustToJson(t, merged))
+	}
+}
+
+func TestMerge_CyclicMoves(t *testing.T) {
+	ours := newTestTree()
+	_ = MoveBlock(ours, "page1", "page2", 0)
+
+	theirs := newTestTree()
+	_ = MoveBlock(theirs, "page2", "page1", 0)
+
+	result := Merge(newTestTree(), ours, theirs)
+
+	conflicts := result.Conflicts()
+
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictMove || conflicts[0].BlockID != "page2" {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+
+	result.Resolve(conflicts[0], ResolutionOurs)
+
+	merged := mustMerge(t, result)
+
+	if FindParent(merged, "page1").ID() != "page2" || FindParent(merged, "page2").ID() != "document1" {
+		t.Errorf("unexpected merged tree %s", mustToJson(t, merged))
+	}
+
+	result.Resolve(conflicts[0], ResolutionTheirs)
+
+	if _, err := result.Block(); !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("expected the move of page1 to conflict, got %v", err)
+	}
+
+	result.ResolveAll(ResolutionTheirs)
+
+	merged = mustMerge(t, result)
+
+	if FindParent(merged, "page2").ID() != "page1" || FindParent(merged, "page1").ID() != "document1" {
+		t.Errorf("unexpected merged tree %s", mustToJson(t, merged))
+	}
+
+	result.Resolve(Conflict{Kind: ConflictMove, BlockID: "page1"}, ResolutionOurs)
+
+	if _, err := result.Block(); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("expected ErrCyclicMove, got %v", err)
+	}
+}
+
+func TestMerge_DeleteModify(t *testing.T) {
+	ours := newTestTree()
+	_ = FindByID(ours, "page1").RemoveChild("image1")
+
+	theirs := newTestTree()
+	FindByID(theirs, "image1").SetParameter("src", "a.png")
+
+	result := Merge(newTestTree(), ours, theirs)
+
+	want := []Conflict{{Kind: ConflictDeleteModify, BlockID: "image1", OursDeleted: true}}
+
+	if got := result.Conflicts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Conflicts() = %+v, want %+v", got, want)
+	}
+
+	result.ResolveAll(ResolutionOurs)
+
+	if FindByID(mustMerge(t, result), "image1") != nil {
+		t.Error("image1 should be deleted")
+	}
+
+	result.ResolveAll(ResolutionTheirs)
+	result.Resolve(want[0], ResolutionTheirs)
+
+	if FindByID(mustMerge(t, result), "image1").Parameter("src") != "a.png" {
+		t.Error("image1 should be kept")
+	}
+}
+
+func TestMerge_DeletedParent(t *testing.T) {
+	ours := newTestTree()
+	_ = ours.RemoveChild("page2")
+
+	theirs := newTestTree()
+	FindByID(theirs, "page2").AddChild(NewBlockBuilder().WithID("heading1").WithType("heading").Build())
+
+	result := Merge(newTestTree(), ours, theirs)
+
+	want := []Conflict{{Kind: ConflictDeleteModify, BlockID: "page2", OursDeleted: true}}
+
+	if got := result.Conflicts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Conflicts() = %+v, want %+v", got, want)
+	}
+
+	result.Resolve(want[0], ResolutionOurs)
+
+	if merged := mustMerge(t, result); FindByID(merged, "page2") != nil || FindByID(merged, "heading1") != nil {
+		t.Errorf("page2 should be deleted with its children: %s", mustToJson(t, merged))
+	}
+
+	result.Resolve(want[0], ResolutionTheirs)
+
+	if got := childIDs(FindByID(mustMerge(t, result), "page2")); !reflect.DeepEqual(got, []string{"image2", "paragraph2", "heading1"}) {
+		t.Errorf("children of page2 = %v", got)
+	}
+}
+
+func TestMerge_RevealedConflicts(t *testing.T) {
+	ours := newTestTree()
+	_ = ours.RemoveChild("page1")
+
+	theirs := newTestTree()
+	FindByID(theirs, "paragraph1").SetParameter("text", "Hello")
+
+	result := Merge(newTestTree(), ours, theirs)
+
+	conflicts := result.Conflicts()
+
+	if len(conflicts) != 1 || conflicts[0].BlockID != "paragraph1" {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+
+	result.Resolve(conflicts[0], ResolutionTheirs)
+
+	unresolved := result.Unresolved()
+
+	if len(unresolved) != 1 || unresolved[0].BlockID != "page1" || unresolved[0].Kind != ConflictDeleteModify {
+		t.Fatalf("unexpected unresolved conflicts %+v", unresolved)
+	}
+
+	result.ResolveAll(ResolutionTheirs)
+
+	merged := mustMerge(t, result)
+
+	if got := childIDs(FindByID(merged, "page1")); !reflect.DeepEqual(got, []string{"paragraph1", "image1"}) {
+		t.Errorf("children of page1 = %v", got)
+	}
+}
@@ -0,0 +1,237 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrBlockNotFound is returned when a block with the ID is not in the tree
+var ErrBlockNotFound = errors.New("block not found")
+
+// ErrIndexOutOfRange is returned when a child index is out of range
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// ErrCyclicMove is returned when moving a block into itself or its descendants
+var ErrCyclicMove = errors.New("cyclic move")
+
+// ErrBlockExists is returned when inserting a block with an ID,
+// which is already used in the tree
+var ErrBlockExists = errors.New("block already exists")
+
+// ErrNilBlock is returned when a nil block is given where a block is required
+var ErrNilBlock = errors.New("block is nil")
+
+// ErrRootBlock is returned when an operation requires
+// a parent, but the block is the root of the tree
+var ErrRootBlock = errors.New("operation not allowed on the root block")
+
+// MoveBlock moves the block with the ID to the parent with newParentID,
+// at the index. The index is the position among the children of the new
+// parent, after the block is removed from its current parent
+//
+// Returns ErrBlockNotFound if either block is not found, ErrRootBlock
+// if the block is the root, ErrCyclicMove if the new parent is the block
+// or one of its descendants, and ErrIndexOutOfRange for an invalid index.
+// The tree is not modified if an error is returned
+func MoveBlock(root BlockInterface, id string, newParentID string, index int) error {
+	path := FindPath(root, id)
+
+	if path == nil {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	if len(path) < 2 {
+		return fmt.Errorf("%w: %q", ErrRootBlock, id)
+	}
+
+	block := path[len(path)-1]
+	parent := path[len(path)-2]
+
+	if FindByID(block, newParentID) != nil {
+		return fmt.Errorf("%w: %q into %q", ErrCyclicMove, id, newParentID)
+	}
+
+	newParent := FindByID(root, newParentID)
+
+	if newParent == nil {
+		return fmt.Errorf("%w: %q", ErrBlockNotFound, newParentID)
+	}
+
+	maxIndex := len(newParent.Children())
+
+	if newParent == parent {
+		maxIndex--
+	}
+
+	if index < 0 || index > maxIndex {
+		return fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+	}
+
+	if err := parent.RemoveChild(id); err != nil {
+		return err
+	}
+
+	return newParent.InsertChildAt(index, block)
+}
+
+// ReplaceBlock replaces the block with the ID by the new block,
+// at the same position in its parent
+//
+// The new block may reuse an ID from the replaced subtree, i.e. to replace
+// a block by an updated copy. Returns ErrNilBlock if the new block is nil,
+// ErrCyclicMove if it is (or contains) an ancestor of the replaced block,
+// and ErrBlockExists if its ID is used elsewhere in the tree.
+// The tree is not modified if an error is returned
+func ReplaceBlock(root BlockInterface, id string, newBlock BlockInterface) error {
+	if newBlock == nil {
+		return fmt.Errorf("%w: replacement of %q", ErrNilBlock, id)
+	}
+
+	parent, index, err := findParentAndIndex(root, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkNewBlock(root, id, newBlock, true); err != nil {
+		return err
+	}
+
+	children := slices.Clone(parent.Children())
+	children[index] = newBlock
+	parent.SetChildren(children)
+
+	return nil
+}
+
+// Wrap replaces the block with the ID by the wrapper block,
+// and appends the block to the children of the wrapper
+//
+// Example:
+//
+//	// page > paragraph1  becomes  page > section > paragraph1
+//	err := ui.Wrap(page, "paragraph1", section)
+//
+// Returns ErrNilBlock if the wrapper is nil, ErrCyclicMove if the wrapper
+// is (or contains) the block or one of its ancestors or descendants, and
+// ErrBlockExists if the ID of the wrapper is already used in the tree.
+// The tree is not modified if an error is returned
+func Wrap(root BlockInterface, id string, wrapper BlockInterface) error {
+	if wrapper == nil {
+		return fmt.Errorf("%w: wrapper of %q", ErrNilBlock, id)
+	}
+
+	parent, index, err := findParentAndIndex(root, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkNewBlock(root, id, wrapper, false); err != nil {
+		return err
+	}
+
+	block := parent.Children()[index]
+
+	children := slices.Clone(parent.Children())
+	children[index] = wrapper
+	parent.SetChildren(children)
+
+	wrapper.AddChild(block)
+
+	return nil
+}
+
+// Unwrap replaces the block with the ID by its children
+//
+// Example:
+//
+//	// page > section > paragraph1  becomes  page > paragraph1
+//	err := ui.Unwrap(page, "section")
+func Unwrap(root BlockInterface, id string) error {
+	parent, index, err := findParentAndIndex(root, id)
+
+	if err != nil {
+		return err
+	}
+
+	block := parent.Children()[index]
+
+	children := slices.Clone(parent.Children())
+	children = slices.Replace(children, index, index+1, block.Children()...)
+	parent.SetChildren(children)
+
+	return nil
+}
+
+// checkNewBlock checks that the new block can be put in the place of the
+// block with the ID, without making the tree cyclic or duplicating an ID
+//
+// If replaced is true the block with the ID leaves the tree, so the new
+// block may reuse the IDs of its subtree. Otherwise (i.e. when wrapping)
+// the block stays in the tree, under the new block
+func checkNewBlock(root BlockInterface, id string, newBlock BlockInterface, replaced bool) error {
+	path := FindPath(root, id)
+	block := path[len(path)-1]
+
+	// the blocks which would end up under the new block
+	cyclic := path[:len(path)-1]
+
+	if !replaced {
+		cyclic = slices.Concat(cyclic, []BlockInterface{block}, slices.Collect(Descendants(block)))
+	}
+
+	if slices.Contains(cyclic, newBlock) {
+		return fmt.Errorf("%w: %q in place of %q", ErrCyclicMove, newBlock.ID(), id)
+	}
+
+	for descendant := range Descendants(newBlock) {
+		if slices.Contains(cyclic, descendant) {
+			return fmt.Errorf("%w: %q contains %q", ErrCyclicMove, newBlock.ID(), descendant.ID())
+		}
+	}
+
+	if FindByID(root, newBlock.ID()) == nil {
+		return nil
+	}
+
+	if replaced && FindByID(block, newBlock.ID()) != nil {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrBlockExists, newBlock.ID())
+}
+
+// checkNotInTree returns ErrBlockExists if the block, or one of its
+// descendants, is already in the tree or uses an ID which is
+func checkNotInTree(root BlockInterface, block BlockInterface) error {
+	for _, b := range slices.Concat([]BlockInterface{block}, slices.Collect(Descendants(block))) {
+		if FindByID(root, b.ID()) != nil {
+			return fmt.Errorf("%w: %q", ErrBlockExists, b.ID())
+		}
+	}
+
+	return nil
+}
+
+// findParentAndIndex returns the parent of the block with the ID,
+// and the index of the block among the children of the parent
+func findParentAndIndex(root BlockInterface, id string) (BlockInterface, int, error) {
+	path := FindPath(root, id)
+
+	if path == nil {
+		return nil, -1, fmt.Errorf("%w: %q", ErrBlockNotFound, id)
+	}
+
+	if len(path) < 2 {
+		return nil, -1, fmt.Errorf("%w: %q", ErrRootBlock, id)
+	}
+
+	block := path[len(path)-1]
+	parent := path[len(path)-2]
+
+	index := slices.Index(parent.Children(), block)
+
+	return parent, index, nil
+}
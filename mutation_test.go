@@ -0,0 +1,320 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func childIDs(block BlockInterface) []string {
+	return blockIDs(block.Children())
+}
+
+func TestBlock_InsertChildAt(t *testing.T) {
+	block := NewBlock()
+
+	for _, tt := range []struct {
+		index int
+		id    string
+		want  []string
+	}{
+		{0, "a", []string{"a"}},
+		{1, "c", []string{"a", "c"}},
+		{1, "b", []string{"a", "b", "c"}},
+		{0, "z", []string{"z", "a", "b", "c"}},
+	} {
+		if err := block.InsertChildAt(tt.index, NewBlockBuilder().WithID(tt.id).Build()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := childIDs(block); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("InsertChildAt(%d, %s) = %v, want %v", tt.index, tt.id, got, tt.want)
+		}
+	}
+
+	if err := block.InsertChildAt(5, NewBlock()); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+
+	if err := block.InsertChildAt(-1, NewBlock()); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+}
+
+func TestBlock_InsertChildAtDoesNotShareSlice(t *testing.T) {
+	block := NewBlock()
+	children := make([]BlockInterface, 0, 10)
+	children = append(children, NewBlockBuilder().WithID("a").Build())
+	block.SetChildren(children)
+
+	if err := block.InsertChildAt(0, NewBlockBuilder().WithID("b").Build()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if children[0].ID() != "a" {
+		t.Error("InsertChildAt must not modify the slice passed to SetChildren")
+	}
+}
+
+func TestBlock_RemoveChild(t *testing.T) {
+	page := FindByID(newTestTree(), "page1")
+
+	if err := page.RemoveChild("paragraph1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := childIDs(page); !reflect.DeepEqual(got, []string{"image1"}) {
+		t.Errorf("RemoveChild() = %v, want [image1]", got)
+	}
+
+	if err := page.RemoveChild("paragraph1"); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+}
+
+func TestMoveBlock(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		newParentID string
+		index       int
+		wantErr     error
+		want        map[string][]string
+	}{
+		{
+			name:        "to another parent",
+			id:          "image1",
+			newParentID: "page2",
+			index:       1,
+			want: map[string][]string{
+				"page1": {"paragraph1"},
+				"page2": {"image2", "image1", "paragraph2"},
+			},
+		},
+		{
+			name:        "reorder in the same parent",
+			id:          "paragraph1",
+			newParentID: "page1",
+			index:       1,
+			want: map[string][]string{
+				"page1": {"image1", "paragraph1"},
+			},
+		},
+		{
+			name:        "up a level",
+			id:          "paragraph2",
+			newParentID: "document1",
+			index:       0,
+			want: map[string][]string{
+				"document1": {"paragraph2", "page1", "page2"},
+				"page2":     {"image2"},
+			},
+		},
+		{
+			name:        "missing block",
+			id:          "missing",
+			newParentID: "page1",
+			wantErr:     ErrBlockNotFound,
+		},
+		{
+			name:        "missing parent",
+			id:          "image1",
+			newParentID: "missing",
+			wantErr:     ErrBlockNotFound,
+		},
+		{
+			name:        "root",
+			id:          "document1",
+			newParentID: "page1",
+			wantErr:     ErrRootBlock,
+		},
+		{
+			name:        "into itself",
+			id:          "page1",
+			newParentID: "page1",
+			wantErr:     ErrCyclicMove,
+		},
+		{
+			name:        "into a descendant",
+			id:          "page1",
+			newParentID: "image1",
+			wantErr:     ErrCyclicMove,
+		},
+		{
+			name:        "index out of range",
+			id:          "paragraph1",
+			newParentID: "page1",
+			index:       2,
+			wantErr:     ErrIndexOutOfRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newTestTree()
+
+			err := MoveBlock(tree, tt.id, tt.newParentID, tt.index)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+
+				if got, _ := tree.ToJson(); got != mustToJson(t, newTestTree()) {
+					t.Errorf("tree modified on error: %s", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for parentID, want := range tt.want {
+				if got := childIDs(FindByID(tree, parentID)); !reflect.DeepEqual(got, want) {
+					t.Errorf("children of %s = %v, want %v", parentID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func mustToJson(t *testing.T, block BlockInterface) string {
+	t.Helper()
+
+	blockJson, err := block.ToJson()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return blockJson
+}
+
+func TestReplaceBlock(t *testing.T) {
+	tree := newTestTree()
+	video := NewBlockBuilder().WithID("video1").WithType("video").Build()
+
+	if err := ReplaceBlock(tree, "image1", video); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := childIDs(FindByID(tree, "page1")); !reflect.DeepEqual(got, []string{"paragraph1", "video1"}) {
+		t.Errorf("children of page1 = %v", got)
+	}
+
+	if err := ReplaceBlock(tree, "missing", video); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if err := ReplaceBlock(tree, "document1", video); !errors.Is(err, ErrRootBlock) {
+		t.Errorf("expected ErrRootBlock, got %v", err)
+	}
+}
+
+func TestWrapAndUnwrap(t *testing.T) {
+	tree := newTestTree()
+	section := NewBlockBuilder().WithID("section1").WithType("section").Build()
+
+	if err := Wrap(tree, "image2", section); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := childIDs(FindByID(tree, "page2")); !reflect.DeepEqual(got, []string{"section1", "paragraph2"}) {
+		t.Errorf("children of page2 = %v", got)
+	}
+
+	if got := childIDs(section); !reflect.DeepEqual(got, []string{"image2"}) {
+		t.Errorf("children of section1 = %v", got)
+	}
+
+	if err := Unwrap(tree, "page2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := childIDs(tree); !reflect.DeepEqual(got, []string{"page1", "section1", "paragraph2"}) {
+		t.Errorf("children of document1 = %v", got)
+	}
+
+	if err := Wrap(tree, "missing", NewBlock()); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if err := Unwrap(tree, "document1"); !errors.Is(err, ErrRootBlock) {
+		t.Errorf("expected ErrRootBlock, got %v", err)
+	}
+
+	if err := Wrap(tree, "page1", FindByID(tree, "paragraph1")); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("expected ErrCyclicMove, got %v", err)
+	}
+}
+
+func TestReplaceBlock_RejectsBlocksInTheTree(t *testing.T) {
+	tree := newTestTree()
+	before := mustToJson(t, tree)
+
+	if err := ReplaceBlock(tree, "paragraph1", FindByID(tree, "page1")); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("replacing by the parent: expected ErrCyclicMove, got %v", err)
+	}
+
+	if err := ReplaceBlock(tree, "paragraph1", tree); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("replacing by the root: expected ErrCyclicMove, got %v", err)
+	}
+
+	wrapper := NewBlockBuilder().WithID("section1").WithType("section").WithChildren([]BlockInterface{FindByID(tree, "page1")}).Build()
+
+	if err := ReplaceBlock(tree, "paragraph1", wrapper); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("replacing by a block containing the parent: expected ErrCyclicMove, got %v", err)
+	}
+
+	if err := ReplaceBlock(tree, "paragraph1", FindByID(tree, "image2")); !errors.Is(err, ErrBlockExists) {
+		t.Errorf("replacing by a block of the tree: expected ErrBlockExists, got %v", err)
+	}
+
+	if err := ReplaceBlock(tree, "paragraph1", nil); !errors.Is(err, ErrNilBlock) {
+		t.Errorf("replacing by nil: expected ErrNilBlock, got %v", err)
+	}
+
+	if after := mustToJson(t, tree); after != before {
+		t.Errorf("the tree must not be modified on error, got %s", after)
+	}
+
+	updated := NewBlockBuilder().WithID("page1").WithType("section").Build()
+
+	if err := ReplaceBlock(tree, "page1", updated); err != nil {
+		t.Fatalf("replacing by a block with the same ID: unexpected error: %v", err)
+	}
+
+	if FindByID(tree, "page1") != updated {
+		t.Error("page1 must be replaced")
+	}
+}
+
+func TestWrap_RejectsBlocksInTheTree(t *testing.T) {
+	tree := newTestTree()
+	before := mustToJson(t, tree)
+
+	if err := Wrap(tree, "paragraph1", FindByID(tree, "page1")); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("wrapping in the parent: expected ErrCyclicMove, got %v", err)
+	}
+
+	if err := Wrap(tree, "paragraph1", tree); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("wrapping in the root: expected ErrCyclicMove, got %v", err)
+	}
+
+	if err := Wrap(tree, "paragraph1", FindByID(tree, "paragraph1")); !errors.Is(err, ErrCyclicMove) {
+		t.Errorf("wrapping in itself: expected ErrCyclicMove, got %v", err)
+	}
+
+	if err := Wrap(tree, "paragraph1", FindByID(tree, "page2")); !errors.Is(err, ErrBlockExists) {
+		t.Errorf("wrapping in a block of the tree: expected ErrBlockExists, got %v", err)
+	}
+
+	if err := Wrap(tree, "paragraph1", nil); !errors.Is(err, ErrNilBlock) {
+		t.Errorf("wrapping in nil: expected ErrNilBlock, got %v", err)
+	}
+
+	if after := mustToJson(t, tree); after != before {
+		t.Errorf("the tree must not be modified on error, got %s", after)
+	}
+}
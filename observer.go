@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"slices"
+	"sync"
+)
+
+// EventKind is the kind of a change event
+type EventKind string
+
+const (
+	// EventIDChanged is emitted by SetID
+	EventIDChanged EventKind = "id_changed"
+
+	// EventTypeChanged is emitted by SetType
+	EventTypeChanged EventKind = "type_changed"
+
+	// EventContentChanged is emitted by SetContent
+	EventContentChanged EventKind = "content_changed"
+
+	// EventParameterChanged is emitted by SetParameter, the values
+	// are strings (the old value is nil, if the parameter was not set)
+	EventParameterChanged EventKind = "parameter_changed"
+
+	// EventParametersChanged is emitted by SetParameters,
+	// the values are map[string]string
+	EventParametersChanged EventKind = "parameters_changed"
+
+	// EventChildrenChanged is emitted by SetChildren, AddChild, AddChildren,
+	// InsertChildAt and RemoveChild, the values are []BlockInterface
+	EventChildrenChanged EventKind = "children_changed"
+)
+
+// Event describes a change of a block in an observed tree
+type Event struct {
+	// Kind is the kind of the change
+	Kind EventKind
+
+	// Block is the changed block
+	Block BlockInterface
+
+	// Key is the parameter key, for EventParameterChanged
+	Key string
+
+	// OldValue and NewValue are the values before and after the change,
+	// their type depends on the Kind. They must not be modified
+	OldValue any
+	NewValue any
+}
+
+// EventFilter selects the events delivered to a subscriber,
+// empty fields match any block
+type EventFilter struct {
+	// BlockID matches events of the block with the ID
+	// (the old or the new ID, for EventIDChanged)
+	BlockID string
+
+	// BlockType matches events of blocks of the type
+	// (the old or the new type, for EventTypeChanged)
+	BlockType string
+}
+
+// TreeObserver delivers the changes made to the blocks of a tree
+// to its subscribers
+//
+// Only *Block instances (and types embedding Block) can be observed.
+// Blocks added to an observed tree are observed as well, and blocks
+// removed from it are no longer observed. Events are delivered
+// synchronously, on the goroutine making the change
+type TreeObserver struct {
+	mu          sync.RWMutex
+	root        BlockInterface
+	subscribers map[int]subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	filter EventFilter
+	fn     func(Event)
+}
+
+// observable is implemented by blocks which can be observed
+type observable interface {
+	setObserver(observer *TreeObserver)
+	treeObserver() *TreeObserver
+}
+
+// ObserveTree starts observing the changes of the block and its descendants
+//
+// Example:
+//
+//	observer := ui.ObserveTree(document)
+//	unsubscribe := observer.Subscribe(ui.EventFilter{BlockType: "image"}, func(event ui.Event) {
+//		cache.Invalidate(event.Block.ID())
+//	})
+func ObserveTree(root BlockInterface) *TreeObserver {
+	observer := &TreeObserver{
+		root:        root,
+		subscribers: map[int]subscriber{},
+	}
+
+	observer.attach(root)
+
+	return observer
+}
+
+// Subscribe registers fn to be called for each change matching the filter,
+// and returns a function which cancels the subscription
+func (o *TreeObserver) Subscribe(filter EventFilter, fn func(Event)) (unsubscribe func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := o.nextID
+	o.nextID++
+	o.subscribers[id] = subscriber{filter: filter, fn: fn}
+
+	return func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		delete(o.subscribers, id)
+	}
+}
+
+// Close stops observing the tree, no more events are delivered
+func (o *TreeObserver) Close() {
+	o.detach(o.root)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.subscribers = map[int]subscriber{}
+}
+
+// notify delivers the event to the matching subscribers
+func (o *TreeObserver) notify(event Event) {
+	o.mu.RLock()
+	subscribers := make([]subscriber, 0, len(o.subscribers))
+	ids := make([]int, 0, len(o.subscribers))
+	for id := range o.subscribers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		subscribers = append(subscribers, o.subscribers[id])
+	}
+	o.mu.RUnlock()
+
+	for _, s := range subscribers {
+		if s.filter.matches(event) {
+			s.fn(event)
+		}
+	}
+}
+
+// attach makes the observer observe the block and its descendants
+func (o *TreeObserver) attach(block BlockInterface) {
+	_ = Walk(block, func(b BlockInterface, _ int) error {
+		if observed, ok := b.(observable); ok {
+			observed.setObserver(o)
+		}
+		return nil
+	})
+}
+
+// detach stops the observer observing the block and its descendants
+func (o *TreeObserver) detach(block BlockInterface) {
+	_ = Walk(block, func(b BlockInterface, _ int) error {
+		if observed, ok := b.(observable); ok && observed.treeObserver() == o {
+			observed.setObserver(nil)
+		}
+		return nil
+	})
+}
+
+// childrenChanged observes the added children, and stops observing
+// the removed children
+func (o *TreeObserver) childrenChanged(oldChildren, newChildren []BlockInterface) {
+	for _, child := range oldChildren {
+		if child != nil && !slices.Contains(newChildren, child) {
+			o.detach(child)
+		}
+	}
+
+	for _, child := range newChildren {
+		if child != nil && !slices.Contains(oldChildren, child) {
+			o.attach(child)
+		}
+	}
+}
+
+func (f EventFilter) matches(event Event) bool {
+	id := event.Block.ID()
+	blockType := event.Block.Type()
+
+	if f.BlockID != "" && f.BlockID != id {
+		if event.Kind != EventIDChanged || event.OldValue != f.BlockID {
+			return false
+		}
+	}
+
+	if f.BlockType != "" && f.BlockType != blockType {
+		if event.Kind != EventTypeChanged || event.OldValue != f.BlockType {
+			return false
+		}
+	}
+
+	return true
+}
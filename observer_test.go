@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestObserveTree(t *testing.T) {
+	tree := newTestTree()
+	observer := ObserveTree(tree)
+
+	type eventSummary struct {
+		Kind     EventKind
+		BlockID  string
+		Key      string
+		OldValue any
+		NewValue any
+	}
+
+	got := []eventSummary{}
+	observer.Subscribe(EventFilter{}, func(event Event) {
+		got = append(got, eventSummary{
+			Kind:     event.Kind,
+			BlockID:  event.Block.ID(),
+			Key:      event.Key,
+			OldValue: event.OldValue,
+			NewValue: event.NewValue,
+		})
+	})
+
+	image := FindByID(tree, "image1")
+	image.SetParameter("src", "a.png")
+	image.SetParameter("src", "b.png")
+	image.SetType("video")
+	image.SetContent("Caption")
+	image.SetID("video1")
+	image.SetParameters(map[string]string{"autoplay": "true"})
+
+	want := []eventSummary{
+		{Kind: EventParameterChanged, BlockID: "image1", Key: "src", OldValue: nil, NewValue: "a.png"},
+		{Kind: EventParameterChanged, BlockID: "image1", Key: "src", OldValue: "a.png", NewValue: "b.png"},
+		{Kind: EventTypeChanged, BlockID: "image1", OldValue: "image", NewValue: "video"},
+		{Kind: EventContentChanged, BlockID: "image1", OldValue: "", NewValue: "Caption"},
+		{Kind: EventIDChanged, BlockID: "video1", OldValue: "image1", NewValue: "video1"},
+		{Kind: EventParametersChanged, BlockID: "video1", OldValue: map[string]string{"src": "b.png"}, NewValue: map[string]string{"autoplay": "true"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestObserveTree_Children(t *testing.T) {
+	tree := newTestTree()
+	observer := ObserveTree(tree)
+
+	changed := []string{}
+	observer.Subscribe(EventFilter{}, func(event Event) {
+		changed = append(changed, string(event.Kind)+":"+event.Block.ID())
+	})
+
+	page := FindByID(tree, "page1")
+	heading := NewBlockBuilder().WithID("heading1").WithType("heading").Build()
+	paragraph := FindByID(tree, "paragraph1")
+
+	page.AddChild(heading)
+	heading.SetParameter("level", "1") // added blocks are observed
+
+	if err := page.RemoveChild("paragraph1"); err != nil {
+		t.Fatal(err)
+	}
+	paragraph.SetParameter("content", "Hello") // removed blocks are not observed
+
+	if err := MoveBlock(tree, "image2", "page1", 0); err != nil {
+		t.Fatal(err)
+	}
+	FindByID(tree, "image2").SetParameter("src", "a.png") // moved blocks stay observed
+
+	want := []string{
+		"children_changed:page1",
+		"parameter_changed:heading1",
+		"children_changed:page1",
+		"children_changed:page2",
+		"children_changed:page1",
+		"parameter_changed:image2",
+	}
+
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("events = %v, want %v", changed, want)
+	}
+}
+
+func TestObserveTree_Filters(t *testing.T) {
+	tree := newTestTree()
+	observer := ObserveTree(tree)
+
+	byID := 0
+	byType := 0
+	observer.Subscribe(EventFilter{BlockID: "image1"}, func(Event) { byID++ })
+	observer.Subscribe(EventFilter{BlockType: "image"}, func(Event) { byType++ })
+
+	FindByID(tree, "image1").SetParameter("src", "a.png")    // both
+	FindByID(tree, "image2").SetParameter("src", "a.png")    // by type
+	FindByID(tree, "paragraph1").SetParameter("content", "") // none
+	FindByID(tree, "image1").SetType("video")                // both, old type matches
+	FindByID(tree, "image1").SetParameter("src", "b.png")    // by ID only
+
+	if byID != 3 {
+		t.Errorf("events by ID = %d, want 3", byID)
+	}
+
+	if byType != 3 {
+		t.Errorf("events by type = %d, want 3", byType)
+	}
+}
+
+func TestObserveTree_UnsubscribeAndClose(t *testing.T) {
+	tree := newTestTree()
+	observer := ObserveTree(tree)
+
+	count := 0
+	unsubscribe := observer.Subscribe(EventFilter{}, func(Event) { count++ })
+
+	tree.SetParameter("a", "1")
+	unsubscribe()
+	tree.SetParameter("a", "2")
+
+	if count != 1 {
+		t.Errorf("events = %d, want 1", count)
+	}
+
+	observer.Subscribe(EventFilter{}, func(Event) { count++ })
+	observer.Close()
+	FindByID(tree, "image1").SetParameter("a", "3")
+
+	if count != 1 {
+		t.Errorf("events after close = %d, want 1", count)
+	}
+}
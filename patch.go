@@ -0,0 +1,534 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPatch is returned for malformed patch operations
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// ErrPatchPathNotFound is returned when a patch path does not exist
+var ErrPatchPathNotFound = errors.New("patch path not found")
+
+// ErrPatchTestFailed is returned when a "test" patch operation fails
+var ErrPatchTestFailed = errors.New("patch test failed")
+
+// PatchOperation is a JSON Patch (RFC 6902) operation
+//
+// The paths are JSON Pointers (RFC 6901) into the JSON of the block,
+// as returned by ToJson, i.e. /children/0/parameters/width
+type PatchOperation struct {
+	// Op is one of add, remove, replace, move, copy and test
+	Op string `json:"op"`
+
+	// Path is the JSON Pointer the operation applies to
+	Path string `json:"path"`
+
+	// From is the source JSON Pointer of move and copy operations
+	From string `json:"from,omitempty"`
+
+	// Value is the value of add, replace and test operations
+	Value any `json:"value"`
+}
+
+// ApplyPatch applies the JSON Patch (RFC 6902) operations to the block
+//
+// The operations are applied in order, to a copy of the block. If any
+// operation fails, or the result is not a valid block, an error is returned,
+// and no changes are applied. The block itself is never modified
+//
+// Returns:
+// - BlockInterface - the patched copy of the block
+// - error - if the patch cannot be applied
+func ApplyPatch(block BlockInterface, patch []PatchOperation) (BlockInterface, error) {
+	document, err := blockToJsonValue(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for index, operation := range patch {
+		document, err = applyPatchOperation(document, operation)
+
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", index, operation.Op, operation.Path, err)
+		}
+	}
+
+	return jsonValueToBlock(document)
+}
+
+// ApplyPatchJson applies a JSON Patch (RFC 6902) document, a JSON array
+// of operations, to the block (see ApplyPatch)
+func ApplyPatchJson(block BlockInterface, patchJson string) (BlockInterface, error) {
+	patch := []PatchOperation{}
+
+	if err := json.Unmarshal([]byte(patchJson), &patch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return ApplyPatch(block, patch)
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396) to the block
+//
+// Object members of the patch are merged recursively, null members
+// are removed (i.e. {"parameters":{"width":null}} removes a parameter),
+// and any other value (including the children array) replaces the
+// current value. The block itself is never modified
+//
+// Returns:
+// - BlockInterface - the patched copy of the block
+// - error - if the patch is not valid JSON, or the result is not a valid block
+func ApplyMergePatch(block BlockInterface, mergePatchJson string) (BlockInterface, error) {
+	document, err := blockToJsonValue(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var mergePatch any
+
+	if err := json.Unmarshal([]byte(mergePatchJson), &mergePatch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return jsonValueToBlock(applyMergePatch(document, mergePatch))
+}
+
+// CreatePatch returns the JSON Patch (RFC 6902) operations,
+// which transform the old block into the new block
+//
+// Objects are compared member by member, and arrays (i.e. children)
+// element by element by position, so the patch is a faithful but
+// not necessarily minimal set of operations
+func CreatePatch(oldBlock, newBlock BlockInterface) ([]PatchOperation, error) {
+	oldDocument, err := blockToJsonValue(oldBlock)
+
+	if err != nil {
+		return nil, err
+	}
+
+	newDocument, err := blockToJsonValue(newBlock)
+
+	if err != nil {
+		return nil, err
+	}
+
+	patch := []PatchOperation{}
+
+	createPatch("", oldDocument, newDocument, &patch)
+
+	return patch, nil
+}
+
+// == CONVERSION ==============================================================
+
+// blockToJsonValue converts the block to its generic JSON value
+func blockToJsonValue(block BlockInterface) (any, error) {
+	if block == nil {
+		return nil, errors.New("block is nil")
+	}
+
+	blockJson, err := json.Marshal(block.ToJsonObject())
+
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+
+	if err := json.Unmarshal(blockJson, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// jsonValueToBlock converts a generic JSON value to a block
+func jsonValueToBlock(value any) (BlockInterface, error) {
+	blockJson, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBlockFromJson(string(blockJson))
+}
+
+// == JSON PATCH ==============================================================
+
+func applyPatchOperation(document any, operation PatchOperation) (any, error) {
+	path, err := parseJsonPointer(operation.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add":
+		return jsonPointerAdd(document, path, deepCopyJsonValue(operation.Value))
+	case "remove":
+		return jsonPointerRemove(document, path)
+	case "replace":
+		if _, err := jsonPointerGet(document, path); err != nil {
+			return nil, err
+		}
+		return jsonPointerSet(document, path, deepCopyJsonValue(operation.Value))
+	case "move":
+		from, err := parseJsonPointer(operation.From)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move %q into its own child %q", ErrInvalidPatch, operation.From, operation.Path)
+		}
+
+		value, err := jsonPointerGet(document, from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		document, err = jsonPointerRemove(document, from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonPointerAdd(document, path, value)
+	case "copy":
+		from, err := parseJsonPointer(operation.From)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := jsonPointerGet(document, from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonPointerAdd(document, path, deepCopyJsonValue(value))
+	case "test":
+		value, err := jsonPointerGet(document, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(value, normalizeJsonValue(operation.Value)) {
+			return nil, ErrPatchTestFailed
+		}
+
+		return document, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, operation.Op)
+	}
+}
+
+// parseJsonPointer splits a JSON Pointer (RFC 6901) into its unescaped tokens
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// escapeJsonPointerToken escapes a token for use in a JSON Pointer
+func escapeJsonPointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func jsonPointerGet(document any, path []string) (any, error) {
+	value := document
+
+	for i, token := range path {
+		switch container := value.(type) {
+		case map[string]any:
+			child, exists := container[token]
+
+			if !exists {
+				return nil, jsonPointerNotFound(path[:i+1])
+			}
+
+			value = child
+		case []any:
+			index, err := jsonPointerIndex(token, len(container)-1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			value = container[index]
+		default:
+			return nil, jsonPointerNotFound(path[:i+1])
+		}
+	}
+
+	return value, nil
+}
+
+// jsonPointerSet replaces the existing value at the path
+func jsonPointerSet(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(document, path[:len(path)-1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = value
+	case []any:
+		index, err := jsonPointerIndex(token, len(container)-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		container[index] = value
+	default:
+		return nil, jsonPointerNotFound(path)
+	}
+
+	return document, nil
+}
+
+func jsonPointerAdd(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parentPath := path[:len(path)-1]
+	parent, err := jsonPointerGet(document, parentPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = value
+		return document, nil
+	case []any:
+		index := len(container)
+
+		if token != "-" {
+			index, err = jsonPointerIndex(token, len(container))
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return jsonPointerSet(document, parentPath, slices.Insert(slices.Clone(container), index, value))
+	default:
+		return nil, jsonPointerNotFound(path)
+	}
+}
+
+func jsonPointerRemove(document any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the root", ErrInvalidPatch)
+	}
+
+	parentPath := path[:len(path)-1]
+	parent, err := jsonPointerGet(document, parentPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		if _, exists := container[token]; !exists {
+			return nil, jsonPointerNotFound(path)
+		}
+
+		delete(container, token)
+
+		return document, nil
+	case []any:
+		index, err := jsonPointerIndex(token, len(container)-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonPointerSet(document, parentPath, slices.Delete(slices.Clone(container), index, index+1))
+	default:
+		return nil, jsonPointerNotFound(path)
+	}
+}
+
+// jsonPointerIndex parses an array index token, which must be
+// between 0 and maxIndex (both included)
+func jsonPointerIndex(token string, maxIndex int) (int, error) {
+	index, err := strconv.Atoi(token)
+
+	if err != nil || index < 0 || index > maxIndex || (len(token) > 1 && token[0] == '0') {
+		return -1, fmt.Errorf("%w: invalid array index %q", ErrPatchPathNotFound, token)
+	}
+
+	return index, nil
+}
+
+func jsonPointerNotFound(path []string) error {
+	pointer := ""
+
+	for _, token := range path {
+		pointer += "/" + escapeJsonPointerToken(token)
+	}
+
+	return fmt.Errorf("%w: %s", ErrPatchPathNotFound, pointer)
+}
+
+// createPatch appends the operations transforming the old value into the new value
+func createPatch(pointer string, oldValue, newValue any, patch *[]PatchOperation) {
+	oldMap, oldIsMap := oldValue.(map[string]any)
+	newMap, newIsMap := newValue.(map[string]any)
+
+	if oldIsMap && newIsMap {
+		oldKeys := sortedKeys(oldMap)
+
+		for _, key := range oldKeys {
+			childPointer := pointer + "/" + escapeJsonPointerToken(key)
+
+			if _, exists := newMap[key]; !exists {
+				*patch = append(*patch, PatchOperation{Op: "remove", Path: childPointer})
+				continue
+			}
+
+			createPatch(childPointer, oldMap[key], newMap[key], patch)
+		}
+
+		for _, key := range sortedKeys(newMap) {
+			if _, exists := oldMap[key]; !exists {
+				*patch = append(*patch, PatchOperation{Op: "add", Path: pointer + "/" + escapeJsonPointerToken(key), Value: newMap[key]})
+			}
+		}
+
+		return
+	}
+
+	oldSlice, oldIsSlice := oldValue.([]any)
+	newSlice, newIsSlice := newValue.([]any)
+
+	if oldIsSlice && newIsSlice {
+		common := min(len(oldSlice), len(newSlice))
+
+		for i := 0; i < common; i++ {
+			createPatch(pointer+"/"+strconv.Itoa(i), oldSlice[i], newSlice[i], patch)
+		}
+
+		for i := len(oldSlice) - 1; i >= common; i-- {
+			*patch = append(*patch, PatchOperation{Op: "remove", Path: pointer + "/" + strconv.Itoa(i)})
+		}
+
+		for i := common; i < len(newSlice); i++ {
+			*patch = append(*patch, PatchOperation{Op: "add", Path: pointer + "/" + strconv.Itoa(i), Value: newSlice[i]})
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*patch = append(*patch, PatchOperation{Op: "replace", Path: pointer, Value: newValue})
+	}
+}
+
+// == JSON MERGE PATCH ========================================================
+
+func applyMergePatch(target any, mergePatch any) any {
+	patchMap, ok := mergePatch.(map[string]any)
+
+	if !ok {
+		return mergePatch
+	}
+
+	targetMap, ok := target.(map[string]any)
+
+	if !ok {
+		targetMap = map[string]any{}
+	}
+
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+			continue
+		}
+
+		targetMap[key] = applyMergePatch(targetMap[key], value)
+	}
+
+	return targetMap
+}
+
+// == HELPERS =================================================================
+
+// normalizeJsonValue converts a value to its generic JSON form
+// (i.e. ints to float64, structs to maps) for comparison
+func normalizeJsonValue(value any) any {
+	valueJson, err := json.Marshal(value)
+
+	if err != nil {
+		return value
+	}
+
+	var normalized any
+
+	if err := json.Unmarshal(valueJson, &normalized); err != nil {
+		return value
+	}
+
+	return normalized
+}
+
+// deepCopyJsonValue returns a deep copy of a JSON value,
+// so that no maps or slices are shared
+func deepCopyJsonValue(value any) any {
+	return normalizeJsonValue(value)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
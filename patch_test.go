@@ -0,0 +1,189 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tree := newTestTree()
+	FindByID(tree, "image1").SetParameter("src", "a.png")
+	originalJson := mustToJson(t, tree)
+
+	patched, err := ApplyPatchJson(tree, `[
+		{"op":"test","path":"/children/0/children/1/parameters/src","value":"a.png"},
+		{"op":"replace","path":"/children/0/children/1/parameters/src","value":"b.png"},
+		{"op":"add","path":"/children/0/children/1/parameters/alt","value":"An image"},
+		{"op":"remove","path":"/children/1/children/0"},
+		{"op":"add","path":"/children/1/children/-","value":{"id":"heading1","type":"heading"}},
+		{"op":"move","from":"/children/0/children/0","path":"/children/1/children/0"},
+		{"op":"copy","from":"/children/0/type","path":"/parameters/layout"},
+		{"op":"replace","path":"/type","value":"article"}
+	]`)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mustToJson(t, tree) != originalJson {
+		t.Error("ApplyPatch must not modify the original block")
+	}
+
+	image := FindByID(patched, "image1")
+
+	if image.Parameter("src") != "b.png" || image.Parameter("alt") != "An image" {
+		t.Errorf("unexpected image parameters %v", image.Parameters())
+	}
+
+	if FindByID(patched, "image2") != nil {
+		t.Error("image2 should be removed")
+	}
+
+	if got := childIDs(FindByID(patched, "page2")); !reflect.DeepEqual(got, []string{"paragraph1", "paragraph2", "heading1"}) {
+		t.Errorf("children of page2 = %v", got)
+	}
+
+	if patched.Type() != "article" || patched.Parameter("layout") != "page" {
+		t.Errorf("unexpected root %v", patched.ToMap())
+	}
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   []PatchOperation
+		wantErr error
+	}{
+		{
+			name:    "unknown op",
+			patch:   []PatchOperation{{Op: "merge", Path: "/type"}},
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "invalid path",
+			patch:   []PatchOperation{{Op: "remove", Path: "type"}},
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "missing path",
+			patch:   []PatchOperation{{Op: "remove", Path: "/parameters/missing"}},
+			wantErr: ErrPatchPathNotFound,
+		},
+		{
+			name:    "index out of range",
+			patch:   []PatchOperation{{Op: "replace", Path: "/children/5/type", Value: "x"}},
+			wantErr: ErrPatchPathNotFound,
+		},
+		{
+			name:    "test failed",
+			patch:   []PatchOperation{{Op: "test", Path: "/type", Value: "page"}},
+			wantErr: ErrPatchTestFailed,
+		},
+		{
+			name:    "move into own child",
+			patch:   []PatchOperation{{Op: "move", From: "/children/0", Path: "/children/0/children/0"}},
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "invalid block",
+			patch:   []PatchOperation{{Op: "add", Path: "/parameters/width", Value: 100}},
+			wantErr: ErrInvalidFieldType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyPatch(newTestTree(), tt.patch)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := ApplyPatchJson(newTestTree(), `{}`); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("expected ErrInvalidPatch, got %v", err)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tree := newTestTree()
+	tree.SetParameter("title", "Home")
+	tree.SetParameter("theme", "dark")
+
+	patched, err := ApplyMergePatch(tree, `{"type":"article","parameters":{"title":"Welcome","theme":null,"lang":"en"}}`)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{"title": "Welcome", "lang": "en"}
+
+	if !reflect.DeepEqual(patched.Parameters(), want) {
+		t.Errorf("Parameters() = %v, want %v", patched.Parameters(), want)
+	}
+
+	if patched.Type() != "article" || len(patched.Children()) != 2 {
+		t.Errorf("unexpected patched block %v", patched.ToMap())
+	}
+
+	patched, err = ApplyMergePatch(tree, `{"children":[]}`)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(patched.Children()) != 0 {
+		t.Errorf("expected the children to be replaced, got %d", len(patched.Children()))
+	}
+
+	if _, err := ApplyMergePatch(tree, `{"id":null}`); !errors.Is(err, ErrMissingField) {
+		t.Errorf("expected ErrMissingField, got %v", err)
+	}
+
+	if _, err := ApplyMergePatch(tree, `{`); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("expected ErrInvalidPatch, got %v", err)
+	}
+}
+
+func TestCreatePatch(t *testing.T) {
+	oldTree := newTestTree()
+	oldTree.SetParameter("a/b", "1")
+	oldTree.SetParameter("removed", "1")
+
+	newTree := newTestTree()
+	newTree.SetParameter("a/b", "2")
+	newTree.SetParameter("added", "1")
+	FindByID(newTree, "image1").SetType("video")
+	if err := FindByID(newTree, "page2").RemoveChild("image2"); err != nil {
+		t.Fatal(err)
+	}
+	newTree.AddChild(NewBlockBuilder().WithID("page3").WithType("page").Build())
+
+	patch, err := CreatePatch(oldTree, newTree)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	patched, err := ApplyPatch(oldTree, patch)
+
+	if err != nil {
+		t.Fatalf("unexpected error applying created patch: %v", err)
+	}
+
+	if got, want := mustToJson(t, patched), mustToJson(t, newTree); got != want {
+		t.Errorf("patched = %s, want %s", got, want)
+	}
+
+	if patch[0].Op != "replace" || patch[0].Path != "/children/0/children/1/type" {
+		t.Errorf("unexpected first operation %+v", patch[0])
+	}
+
+	emptyPatch, err := CreatePatch(newTestTree(), newTestTree())
+
+	if err != nil || len(emptyPatch) != 0 {
+		t.Errorf("expected an empty patch, got %v, %v", emptyPatch, err)
+	}
+}
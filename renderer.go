@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ErrRendererNotFound is returned when there is no renderer registered
+// for a block type, and no fallback renderer is configured
+var ErrRendererNotFound = errors.New("renderer not found")
+
+// RenderError is returned when rendering is stopped at a block, because
+// the context was cancelled or its deadline exceeded, there is no renderer
+// for its type, or its render function (or the writer) failed
+//
+// BlockID is the innermost block where rendering stopped, and Err
+// the cause, i.e. context.Canceled or ErrRendererNotFound
+type RenderError struct {
+	BlockID   string
+	BlockType string
+	Err       error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("rendering stopped at block %q (type %q): %v", e.BlockID, e.BlockType, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// RenderFunc renders a block as HTML into the writer
+//
+// The renderChildren function renders the children of the block,
+// in order, into the given writer. A RenderFunc decides where
+// (and if) the children are rendered.
+//
+// Example:
+//
+//	renderer.RegisterRenderer("paragraph", func(w io.Writer, block BlockInterface, renderChildren func(io.Writer) error) error {
+//		if _, err := io.WriteString(w, "<p>"+html.EscapeString(block.Parameter("content"))); err != nil {
+//			return err
+//		}
+//		if err := renderChildren(w); err != nil {
+//			return err
+//		}
+//		_, err := io.WriteString(w, "</p>")
+//		return err
+//	})
+type RenderFunc func(w io.Writer, block BlockInterface, renderChildren func(io.Writer) error) error
+
+// Renderer is a thread-safe registry of render functions per block type,
+// which renders a block tree to HTML
+type Renderer struct {
+	mu        sync.RWMutex
+	renderers map[string]RenderFunc
+	fallback  RenderFunc
+}
+
+// NewRenderer creates a new Renderer
+//
+// The default fallback renders the children of blocks with unknown types
+// (without any wrapping markup), or uses ToHTML if the block
+// implements ToHTMLInterface
+func NewRenderer() *Renderer {
+	return &Renderer{
+		renderers: make(map[string]RenderFunc),
+		fallback:  defaultFallbackRenderFunc,
+	}
+}
+
+// RegisterRenderer registers a render function for a block type
+func (r *Renderer) RegisterRenderer(blockType string, renderFunc RenderFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renderers[blockType] = renderFunc
+}
+
+// SetFallback sets the render function used for block types without
+// a registered renderer. Setting it to nil makes rendering unknown block
+// types fail with ErrRendererNotFound
+func (r *Renderer) SetFallback(renderFunc RenderFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = renderFunc
+}
+
+// Render renders the block and its children as HTML into the writer
+func (r *Renderer) Render(w io.Writer, block BlockInterface) error {
+	return r.RenderTo(context.Background(), w, block)
+}
+
+// RenderTo renders the block and its children as HTML into the writer,
+// streaming the output block by block
+//
+// The context is checked before each block is rendered. If it is cancelled,
+// or past its deadline, rendering stops and a *RenderError is returned,
+// naming the block where rendering stopped and wrapping the context error.
+// Other failures (a missing renderer, or an error returned by a RenderFunc
+// or the writer) are returned as a *RenderError too. Output written before
+// that point is left in the writer, so it can be flushed to the client.
+func (r *Renderer) RenderTo(ctx context.Context, w io.Writer, block BlockInterface) error {
+	if block == nil {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &RenderError{BlockID: block.ID(), BlockType: block.Type(), Err: err}
+	}
+
+	renderFunc, err := r.renderFunc(block.Type())
+
+	if err != nil {
+		return &RenderError{BlockID: block.ID(), BlockType: block.Type(), Err: err}
+	}
+
+	renderChildren := func(w io.Writer) error {
+		return r.RenderBlocksTo(ctx, w, block.Children())
+	}
+
+	if err := renderFunc(w, block, renderChildren); err != nil {
+		// already wrapped at the descendant where rendering stopped
+		var renderErr *RenderError
+		if errors.As(err, &renderErr) {
+			return err
+		}
+
+		return &RenderError{BlockID: block.ID(), BlockType: block.Type(), Err: err}
+	}
+
+	return nil
+}
+
+// RenderBlocks renders the blocks, in order, as HTML into the writer
+func (r *Renderer) RenderBlocks(w io.Writer, blocks []BlockInterface) error {
+	return r.RenderBlocksTo(context.Background(), w, blocks)
+}
+
+// RenderBlocksTo renders the blocks, in order, as HTML into the writer,
+// stopping early when the context is done (see RenderTo)
+func (r *Renderer) RenderBlocksTo(ctx context.Context, w io.Writer, blocks []BlockInterface) error {
+	for _, block := range blocks {
+		if err := r.RenderTo(ctx, w, block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ToHTML renders the block and its children to an HTML string
+func (r *Renderer) ToHTML(block BlockInterface) (string, error) {
+	html := &strings.Builder{}
+
+	if err := r.Render(html, block); err != nil {
+		return "", err
+	}
+
+	return html.String(), nil
+}
+
+// renderFunc returns the render function for the block type,
+// or the fallback if none is registered
+func (r *Renderer) renderFunc(blockType string) (RenderFunc, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if renderFunc, exists := r.renderers[blockType]; exists {
+		return renderFunc, nil
+	}
+
+	if r.fallback != nil {
+		return r.fallback, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrRendererNotFound, blockType)
+}
+
+// defaultFallbackRenderFunc uses ToHTML if the block implements
+// ToHTMLInterface, otherwise renders only the children of the block
+func defaultFallbackRenderFunc(w io.Writer, block BlockInterface, renderChildren func(io.Writer) error) error {
+	if htmlBlock, ok := block.(ToHTMLInterface); ok {
+		_, err := io.WriteString(w, htmlBlock.ToHTML())
+		return err
+	}
+
+	return renderChildren(w)
+}
@@ -0,0 +1,274 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestDocument() BlockInterface {
+	paragraph1 := NewBlock()
+	paragraph1.SetID("paragraph1")
+	paragraph1.SetType("paragraph")
+	paragraph1.SetParameter("content", "Hello, world!")
+
+	paragraph2 := NewBlock()
+	paragraph2.SetID("paragraph2")
+	paragraph2.SetType("paragraph")
+	paragraph2.SetParameter("content", "Goodbye & farewell!")
+
+	page := NewBlock()
+	page.SetID("page1")
+	page.SetType("page")
+	page.AddChild(paragraph1)
+	page.AddChild(paragraph2)
+
+	document := NewBlock()
+	document.SetID("document1")
+	document.SetType("document")
+	document.AddChild(page)
+
+	return document
+}
+
+func wrapRenderFunc(open, close string) RenderFunc {
+	return func(w io.Writer, block BlockInterface, renderChildren func(io.Writer) error) error {
+		if _, err := io.WriteString(w, open); err != nil {
+			return err
+		}
+		if err := renderChildren(w); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, close)
+		return err
+	}
+}
+
+func TestRenderer_ToHTML(t *testing.T) {
+	renderer := NewRenderer()
+	renderer.RegisterRenderer("document", wrapRenderFunc("<html><body>", "</body></html>"))
+	renderer.RegisterRenderer("page", wrapRenderFunc("<main>", "</main>"))
+	renderer.RegisterRenderer("paragraph", func(w io.Writer, block BlockInterface, _ func(io.Writer) error) error {
+		_, err := io.WriteString(w, "<p>"+html.EscapeString(block.Parameter("content"))+"</p>")
+		return err
+	})
+
+	got, err := renderer.ToHTML(newTestDocument())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "<html><body><main><p>Hello, world!</p><p>Goodbye &amp; farewell!</p></main></body></html>"
+
+	if got != want {
+		t.Errorf("ToHTML() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderer_DefaultFallback(t *testing.T) {
+	renderer := NewRenderer()
+	renderer.RegisterRenderer("paragraph", func(w io.Writer, block BlockInterface, _ func(io.Writer) error) error {
+		_, err := io.WriteString(w, "<p>"+block.Parameter("content")+"</p>")
+		return err
+	})
+
+	got, err := renderer.ToHTML(newTestDocument())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "<p>Hello, world!</p><p>Goodbye & farewell!</p>"
+
+	if got != want {
+		t.Errorf("ToHTML() = %q, want %q", got, want)
+	}
+}
+
+type testHTMLBlock struct {
+	Block
+}
+
+func (b *testHTMLBlock) ToHTML() string {
+	return "<hr>"
+}
+
+func TestRenderer_DefaultFallbackToHTMLInterface(t *testing.T) {
+	divider := &testHTMLBlock{}
+	divider.SetType("divider")
+
+	page := NewBlock()
+	page.SetType("page")
+	page.AddChild(divider)
+
+	got, err := NewRenderer().ToHTML(page)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "<hr>" {
+		t.Errorf("ToHTML() = %q, want %q", got, "<hr>")
+	}
+}
+
+func TestRenderer_CustomFallback(t *testing.T) {
+	renderer := NewRenderer()
+	renderer.SetFallback(func(w io.Writer, block BlockInterface, renderChildren func(io.Writer) error) error {
+		return wrapRenderFunc(`<div class="`+block.Type()+`">`, "</div>")(w, block, renderChildren)
+	})
+
+	got, err := renderer.ToHTML(newTestDocument())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `<div class="document"><div class="page"><div class="paragraph"></div><div class="paragraph"></div></div></div>`
+
+	if got != want {
+		t.Errorf("ToHTML() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderer_NoFallback(t *testing.T) {
+	renderer := NewRenderer()
+	renderer.SetFallback(nil)
+
+	_, err := renderer.ToHTML(newTestDocument())
+
+	if !errors.Is(err, ErrRendererNotFound) {
+		t.Fatalf("expected ErrRendererNotFound, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "document") {
+		t.Errorf("error %q does not mention the block type", err.Error())
+	}
+}
+
+func TestRenderer_RenderErrorNamesTheBlock(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	renderer := NewRenderer()
+	renderer.RegisterRenderer("paragraph", func(w io.Writer, block BlockInterface, _ func(io.Writer) error) error {
+		if block.ID() == "paragraph2" {
+			return errFailed
+		}
+		return nil
+	})
+
+	renderer.RegisterRenderer("page", func(w io.Writer, block BlockInterface, renderChildren func(io.Writer) error) error {
+		if err := renderChildren(w); err != nil {
+			return fmt.Errorf("page: %w", err)
+		}
+		return nil
+	})
+
+	err := renderer.Render(io.Discard, newTestDocument())
+
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("expected *RenderError, got %T %v", err, err)
+	}
+
+	if renderErr.BlockID != "paragraph2" || !errors.Is(err, errFailed) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if strings.Count(err.Error(), "rendering stopped") != 1 {
+		t.Errorf("the error must be wrapped once, got %q", err.Error())
+	}
+
+	renderer.SetFallback(nil)
+
+	err = renderer.Render(io.Discard, newTestDocument())
+
+	if !errors.As(err, &renderErr) || renderErr.BlockID != "document1" || !errors.Is(err, ErrRendererNotFound) {
+		t.Errorf("expected *RenderError for document1 wrapping ErrRendererNotFound, got %v", err)
+	}
+}
+
+func TestRenderer_NilBlock(t *testing.T) {
+	got, err := NewRenderer().ToHTML(nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "" {
+		t.Errorf("ToHTML() = %q, want empty string", got)
+	}
+}
+
+func TestRenderer_RenderTo(t *testing.T) {
+	renderer := NewRenderer()
+	renderer.RegisterRenderer("paragraph", func(w io.Writer, block BlockInterface, _ func(io.Writer) error) error {
+		_, err := io.WriteString(w, "<p>"+block.Parameter("content")+"</p>")
+		return err
+	})
+
+	output := &strings.Builder{}
+
+	if err := renderer.RenderTo(context.Background(), output, newTestDocument()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "<p>Hello, world!</p><p>Goodbye & farewell!</p>"
+
+	if output.String() != want {
+		t.Errorf("RenderTo() = %q, want %q", output.String(), want)
+	}
+}
+
+func TestRenderer_RenderToCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	renderer := NewRenderer()
+	renderer.RegisterRenderer("paragraph", func(w io.Writer, block BlockInterface, _ func(io.Writer) error) error {
+		_, err := io.WriteString(w, "<p>"+block.Parameter("content")+"</p>")
+		cancel() // cancel after the first paragraph is rendered
+		return err
+	})
+
+	output := &strings.Builder{}
+
+	err := renderer.RenderTo(ctx, output, newTestDocument())
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("expected *RenderError, got %T", err)
+	}
+
+	if renderErr.BlockID != "paragraph2" {
+		t.Errorf("BlockID = %q, want %q", renderErr.BlockID, "paragraph2")
+	}
+
+	if output.String() != "<p>Hello, world!</p>" {
+		t.Errorf("partial output = %q, want %q", output.String(), "<p>Hello, world!</p>")
+	}
+}
+
+func TestRenderer_RenderToDeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	err := NewRenderer().RenderTo(ctx, io.Discard, newTestDocument())
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "document1") {
+		t.Errorf("error %q does not name the block ID", err.Error())
+	}
+}
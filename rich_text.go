@@ -0,0 +1,532 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidRichText is returned when rich text can not be decoded,
+// or uses unknown marks or invalid attributes
+var ErrInvalidRichText = errors.New("invalid rich text")
+
+// Mark is an inline formatting applied to a text run
+type Mark string
+
+const (
+	MarkBold          Mark = "bold"
+	MarkItalic        Mark = "italic"
+	MarkUnderline     Mark = "underline"
+	MarkStrikethrough Mark = "strikethrough"
+	MarkCode          Mark = "code"
+
+	// MarkLink links the text to the URL in the AttributeHref attribute
+	MarkLink Mark = "link"
+
+	// MarkMention mentions the entity in the AttributeMention attribute
+	// (i.e. a user ID), the text is the label shown for it
+	MarkMention Mark = "mention"
+)
+
+const (
+	// AttributeHref is the URL of a MarkLink run
+	AttributeHref = "href"
+
+	// AttributeMention is the ID of the entity of a MarkMention run
+	AttributeMention = "mention"
+)
+
+// markOrder is the canonical order of the marks, from the outermost
+// to the innermost, used by Normalize and the renderers
+var markOrder = []Mark{
+	MarkLink,
+	MarkMention,
+	MarkBold,
+	MarkItalic,
+	MarkUnderline,
+	MarkStrikethrough,
+	MarkCode,
+}
+
+// TextRun is a span of text sharing the same marks and attributes
+type TextRun struct {
+	Text       string            `json:"text"`
+	Marks      []Mark            `json:"marks,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// NewTextRun creates a text run with the marks
+func NewTextRun(text string, marks ...Mark) TextRun {
+	return TextRun{Text: text, Marks: marks}
+}
+
+// NewLinkRun creates a text run linking to the URL
+func NewLinkRun(text string, href string, marks ...Mark) TextRun {
+	return TextRun{
+		Text:       text,
+		Marks:      append([]Mark{MarkLink}, marks...),
+		Attributes: map[string]string{AttributeHref: href},
+	}
+}
+
+// NewMentionRun creates a text run mentioning the entity with the ID
+func NewMentionRun(label string, id string) TextRun {
+	return TextRun{
+		Text:       label,
+		Marks:      []Mark{MarkMention},
+		Attributes: map[string]string{AttributeMention: id},
+	}
+}
+
+// HasMark checks if the run has the mark
+func (r TextRun) HasMark(mark Mark) bool {
+	return slices.Contains(r.Marks, mark)
+}
+
+// Attribute returns the value of the attribute, or an empty string
+func (r TextRun) Attribute(key string) string {
+	return r.Attributes[key]
+}
+
+// RichText is inline text made of a sequence of text runs
+//
+// It is stored in the content of a block as JSON (see SetBlockRichText),
+// and rendered with ToHTML, ToMarkdown and PlainText
+type RichText []TextRun
+
+// NewRichTextFromJson creates rich text from its JSON representation,
+// a JSON array of text runs
+func NewRichTextFromJson(richTextJson string) (RichText, error) {
+	richText := RichText{}
+
+	if err := json.Unmarshal([]byte(richTextJson), &richText); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRichText, err)
+	}
+
+	return richText, nil
+}
+
+// ToJson returns the JSON representation of the rich text
+func (t RichText) ToJson() (string, error) {
+	if t == nil {
+		t = RichText{}
+	}
+
+	richTextJson, err := json.Marshal(t)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(richTextJson), nil
+}
+
+// Validate checks that all the marks are known, and that links
+// and mentions have their attributes
+//
+// Link URLs must be relative, or use the http, https or mailto scheme
+func (t RichText) Validate() error {
+	for index, run := range t {
+		for _, mark := range run.Marks {
+			if !slices.Contains(markOrder, mark) {
+				return fmt.Errorf("%w: run %d: unknown mark %q", ErrInvalidRichText, index, mark)
+			}
+		}
+
+		if run.HasMark(MarkLink) && !isSafeHref(run.Attribute(AttributeHref)) {
+			return fmt.Errorf("%w: run %d: invalid link %q", ErrInvalidRichText, index, run.Attribute(AttributeHref))
+		}
+
+		if run.HasMark(MarkMention) && run.Attribute(AttributeMention) == "" {
+			return fmt.Errorf("%w: run %d: mention without %q attribute", ErrInvalidRichText, index, AttributeMention)
+		}
+	}
+
+	return nil
+}
+
+// Normalize returns the rich text in its canonical form
+//
+// Empty runs are removed, the marks of each run are deduplicated and
+// sorted, and adjacent runs with the same marks and attributes are
+// merged into one. The original rich text is not modified
+func (t RichText) Normalize() RichText {
+	normalized := RichText{}
+
+	for _, run := range t {
+		if run.Text == "" {
+			continue
+		}
+
+		run = normalizeTextRun(run)
+
+		if last := len(normalized) - 1; last >= 0 && sameFormatting(normalized[last], run) {
+			normalized[last].Text += run.Text
+			continue
+		}
+
+		normalized = append(normalized, run)
+	}
+
+	return normalized
+}
+
+// PlainText returns the text of the runs, without any formatting
+func (t RichText) PlainText() string {
+	text := strings.Builder{}
+
+	for _, run := range t {
+		text.WriteString(run.Text)
+	}
+
+	return text.String()
+}
+
+// ToHTML renders the rich text as HTML, with the text escaped
+//
+// Links with an unsafe URL (i.e. javascript:) are rendered as plain text,
+// and unknown marks are ignored
+func (t RichText) ToHTML() string {
+	out := strings.Builder{}
+
+	for _, run := range t.Normalize() {
+		marks := renderedMarks(run)
+
+		for _, mark := range marks {
+			out.WriteString(htmlOpenTag(mark, run))
+		}
+
+		out.WriteString(strings.ReplaceAll(html.EscapeString(run.Text), "\n", "<br>"))
+
+		for _, mark := range slices.Backward(marks) {
+			out.WriteString(htmlCloseTag(mark))
+		}
+	}
+
+	return out.String()
+}
+
+// ToMarkdown renders the rich text as CommonMark, with the text escaped
+//
+// The text is escaped so that it renders inline, also the markers of
+// lists, headings and other blocks at the start of a line. Line breaks are
+// rendered as hard breaks, as in ToHTML (except in code, where Markdown
+// has none). Underline and mentions have no Markdown syntax, so only their
+// text is rendered. Links with an unsafe URL are rendered as plain text,
+// and unknown marks are ignored
+func (t RichText) ToMarkdown() string {
+	out := strings.Builder{}
+
+	for _, run := range t.Normalize() {
+		lineStart := out.Len() == 0 || strings.HasSuffix(out.String(), "\n")
+		out.WriteString(markdownRun(run, lineStart))
+	}
+
+	return out.String()
+}
+
+// BlockRichText returns the content of the block as rich text
+//
+// Content which is valid rich text JSON (as stored by SetBlockRichText)
+// is decoded. Any other content (i.e. plain text set with SetContent, even
+// if it starts with "[") is returned as a single run without marks, and
+// empty content as empty rich text. The content has no marker of its format,
+// so plain text which happens to be valid rich text JSON is decoded too
+func BlockRichText(block BlockInterface) RichText {
+	content := block.Content()
+
+	if content == "" {
+		return RichText{}
+	}
+
+	if strings.HasPrefix(content, "[") {
+		richText, err := NewRichTextFromJson(content)
+
+		// SetBlockRichText stores only valid, non empty rich text
+		if err == nil && len(richText) > 0 && richText.Validate() == nil {
+			return richText
+		}
+	}
+
+	return RichText{NewTextRun(content)}
+}
+
+// SetBlockRichText validates and normalizes the rich text,
+// and stores it as JSON in the content of the block
+func SetBlockRichText(block BlockInterface, richText RichText) error {
+	if err := richText.Validate(); err != nil {
+		return err
+	}
+
+	normalized := richText.Normalize()
+
+	if len(normalized) == 0 {
+		block.SetContent("")
+		return nil
+	}
+
+	richTextJson, err := normalized.ToJson()
+
+	if err != nil {
+		return err
+	}
+
+	block.SetContent(richTextJson)
+	return nil
+}
+
+// normalizeTextRun returns a copy of the run, with its marks deduplicated
+// and in canonical order, and without empty attributes
+func normalizeTextRun(run TextRun) TextRun {
+	marks := slices.Clone(run.Marks)
+
+	slices.SortFunc(marks, func(a, b Mark) int {
+		if rankA, rankB := markRank(a), markRank(b); rankA != rankB {
+			return rankA - rankB
+		}
+		return strings.Compare(string(a), string(b))
+	})
+
+	marks = slices.Compact(marks)
+
+	if len(marks) == 0 {
+		marks = nil
+	}
+
+	attributes := maps.Clone(run.Attributes)
+
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+
+	return TextRun{Text: run.Text, Marks: marks, Attributes: attributes}
+}
+
+// markRank returns the position of the mark in the canonical order,
+// unknown marks are placed after the known ones
+func markRank(mark Mark) int {
+	if rank := slices.Index(markOrder, mark); rank >= 0 {
+		return rank
+	}
+
+	return len(markOrder)
+}
+
+// sameFormatting checks if two normalized runs have the same marks and attributes
+func sameFormatting(a TextRun, b TextRun) bool {
+	return slices.Equal(a.Marks, b.Marks) && maps.Equal(a.Attributes, b.Attributes)
+}
+
+// renderedMarks returns the known marks of a normalized run,
+// without links having an unsafe URL
+func renderedMarks(run TextRun) []Mark {
+	marks := []Mark{}
+
+	for _, mark := range run.Marks {
+		if !slices.Contains(markOrder, mark) {
+			continue
+		}
+
+		if mark == MarkLink && !isSafeHref(run.Attribute(AttributeHref)) {
+			continue
+		}
+
+		marks = append(marks, mark)
+	}
+
+	return marks
+}
+
+// isSafeHref checks if the URL is relative, or uses the http, https or mailto scheme
+func isSafeHref(href string) bool {
+	if strings.TrimSpace(href) == "" {
+		return false
+	}
+
+	parsed, err := url.Parse(href)
+
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+func htmlOpenTag(mark Mark, run TextRun) string {
+	switch mark {
+	case MarkLink:
+		return `<a href="` + html.EscapeString(run.Attribute(AttributeHref)) + `">`
+	case MarkMention:
+		return `<span class="mention" data-mention="` + html.EscapeString(run.Attribute(AttributeMention)) + `">`
+	case MarkBold:
+		return "<strong>"
+	case MarkItalic:
+		return "<em>"
+	case MarkUnderline:
+		return "<u>"
+	case MarkStrikethrough:
+		return "<s>"
+	case MarkCode:
+		return "<code>"
+	}
+
+	return ""
+}
+
+func htmlCloseTag(mark Mark) string {
+	switch mark {
+	case MarkLink:
+		return "</a>"
+	case MarkMention:
+		return "</span>"
+	case MarkBold:
+		return "</strong>"
+	case MarkItalic:
+		return "</em>"
+	case MarkUnderline:
+		return "</u>"
+	case MarkStrikethrough:
+		return "</s>"
+	case MarkCode:
+		return "</code>"
+	}
+
+	return ""
+}
+
+// markdownDelimiters are the delimiters of the emphasis-like marks
+var markdownDelimiters = map[Mark]string{
+	MarkBold:          "**",
+	MarkItalic:        "_",
+	MarkStrikethrough: "~~",
+}
+
+// markdownRun renders a normalized run as Markdown, lineStart is true
+// if the run starts at the start of a line
+//
+// Emphasis delimiters must not be next to whitespace, so the leading
+// and trailing whitespace of the text is moved outside of them
+func markdownRun(run TextRun, lineStart bool) string {
+	marks := renderedMarks(run)
+
+	if slices.Contains(marks, MarkCode) {
+		return markdownWrap(markdownCodeSpan(run.Text), marks, run)
+	}
+
+	// a backslash before a line break makes it a hard break
+	return strings.ReplaceAll(markdownWrap(markdownEscape(run.Text, lineStart), marks, run), "\n", "\\\n")
+}
+
+// markdownWrap wraps the rendered text of a run in the delimiters
+// of its marks, and in a link
+func markdownWrap(text string, marks []Mark, run TextRun) string {
+	core := strings.TrimFunc(text, unicode.IsSpace)
+
+	if core == "" {
+		return text
+	}
+
+	start := strings.Index(text, core)
+	leading, trailing := text[:start], text[start+len(core):]
+
+	for _, mark := range slices.Backward(marks) {
+		if delimiter, ok := markdownDelimiters[mark]; ok {
+			core = delimiter + core + delimiter
+		}
+	}
+
+	if slices.Contains(marks, MarkLink) {
+		core = "[" + core + "](" + markdownEscapeHref(run.Attribute(AttributeHref)) + ")"
+	}
+
+	return leading + core + trailing
+}
+
+// markdownSpecialCharacters are escaped with a backslash in Markdown text
+const markdownSpecialCharacters = "\\`*_[]()<>~#|&!"
+
+// markdownLineStartCharacters are escaped with a backslash at the start
+// of a line, where they start a list, a thematic break or a heading
+const markdownLineStartCharacters = "-+="
+
+// markdownEscape escapes the text, lineStart is true if the text
+// starts at the start of a line
+//
+// At the start of each line (after the indentation), the markers of lists,
+// thematic breaks and setext headings are escaped too, and the dot of
+// an ordered list marker (i.e. "1.")
+func markdownEscape(text string, lineStart bool) string {
+	out := strings.Builder{}
+	runes := []rune(text)
+	orderedListDot := -1
+
+	for index, r := range runes {
+		switch {
+		case r == '\n':
+			lineStart = true
+		case lineStart && (r == ' ' || r == '\t'):
+		case lineStart:
+			lineStart = false
+
+			if strings.ContainsRune(markdownLineStartCharacters, r) {
+				out.WriteByte('\\')
+			}
+
+			digits := index
+
+			for digits < len(runes) && '0' <= runes[digits] && runes[digits] <= '9' {
+				digits++
+			}
+
+			if digits > index && digits < len(runes) && runes[digits] == '.' {
+				orderedListDot = digits
+			}
+		}
+
+		if strings.ContainsRune(markdownSpecialCharacters, r) || index == orderedListDot {
+			out.WriteByte('\\')
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+// markdownCodeSpan wraps the text in a code span, using a backtick
+// fence longer than any run of backticks in the text
+func markdownCodeSpan(text string) string {
+	longest, current := 0, 0
+
+	for _, r := range text {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+
+	fence := strings.Repeat("`", longest+1)
+
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+
+	return fence + text + fence
+}
+
+func markdownEscapeHref(href string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(href)
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRichText_Normalize(t *testing.T) {
+	richText := RichText{
+		NewTextRun("Hello "),
+		NewTextRun(""),
+		NewTextRun("big", MarkItalic, MarkBold),
+		NewTextRun(" bold", MarkBold, MarkItalic, MarkBold),
+		{Text: " world", Attributes: map[string]string{}},
+		NewLinkRun("docs", "/docs"),
+		NewLinkRun(" page", "/docs"),
+		NewLinkRun("other", "/other"),
+	}
+
+	want := RichText{
+		{Text: "Hello "},
+		{Text: "big bold", Marks: []Mark{MarkBold, MarkItalic}},
+		{Text: " world"},
+		{Text: "docs page", Marks: []Mark{MarkLink}, Attributes: map[string]string{AttributeHref: "/docs"}},
+		{Text: "other", Marks: []Mark{MarkLink}, Attributes: map[string]string{AttributeHref: "/other"}},
+	}
+
+	got := richText.Normalize()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize() = %#v, want %#v", got, want)
+	}
+
+	if richText[2].Marks[0] != MarkItalic {
+		t.Error("Normalize must not modify the original rich text")
+	}
+
+	if !reflect.DeepEqual(got.Normalize(), got) {
+		t.Error("Normalize must be idempotent")
+	}
+}
+
+func TestRichText_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		richText RichText
+		wantErr  bool
+	}{
+		{"valid", RichText{NewTextRun("a", MarkBold, MarkCode), NewLinkRun("b", "https://example.com"), NewMentionRun("@c", "user1")}, false},
+		{"relative and mailto links", RichText{NewLinkRun("a", "/path?q=1"), NewLinkRun("b", "mailto:a@example.com")}, false},
+		{"unknown mark", RichText{NewTextRun("a", "blink")}, true},
+		{"javascript link", RichText{NewLinkRun("a", "JavaScript:alert(1)")}, true},
+		{"link without href", RichText{NewTextRun("a", MarkLink)}, true},
+		{"mention without id", RichText{NewTextRun("a", MarkMention)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.richText.Validate()
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidRichText) {
+				t.Errorf("error must match ErrInvalidRichText, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRichText_PlainText(t *testing.T) {
+	richText := RichText{NewTextRun("Hello, "), NewMentionRun("@alice", "user1"), NewTextRun("!", MarkBold)}
+
+	if got := richText.PlainText(); got != "Hello, @alice!" {
+		t.Errorf("PlainText() = %q", got)
+	}
+}
+
+func TestRichText_ToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		richText RichText
+		want     string
+	}{
+		{"plain text is escaped", RichText{NewTextRun(`<script>"x" & y</script>`)}, `&lt;script&gt;&#34;x&#34; &amp; y&lt;/script&gt;`},
+		{"marks", RichText{NewTextRun("a", MarkCode, MarkBold), NewTextRun("b", MarkItalic, MarkUnderline, MarkStrikethrough)}, `<strong><code>a</code></strong><em><u><s>b</s></u></em>`},
+		{"adjacent runs are merged", RichText{NewTextRun("a", MarkBold), NewTextRun("b", MarkBold)}, `<strong>ab</strong>`},
+		{"link", RichText{NewLinkRun("docs", `/docs?a=1&b="2"`, MarkBold)}, `<a href="/docs?a=1&amp;b=&#34;2&#34;"><strong>docs</strong></a>`},
+		{"unsafe link", RichText{NewLinkRun("click", "javascript:alert(1)")}, `click`},
+		{"mention", RichText{NewMentionRun("@alice", `user"1`)}, `<span class="mention" data-mention="user&#34;1">@alice</span>`},
+		{"line breaks", RichText{NewTextRun("a\nb")}, `a<br>b`},
+		{"unknown marks are ignored", RichText{NewTextRun("a", "blink", MarkBold)}, `<strong>a</strong>`},
+		{"empty", nil, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.richText.ToHTML(); got != tt.want {
+				t.Errorf("ToHTML() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRichText_ToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		richText RichText
+		want     string
+	}{
+		{"plain text is escaped", RichText{NewTextRun("1 * 2 = [x]_y_")}, `1 \* 2 = \[x\]\_y\_`},
+		{"marks", RichText{NewTextRun("a", MarkBold, MarkItalic), NewTextRun(" "), NewTextRun("b", MarkStrikethrough)}, `**_a_** ~~b~~`},
+		{"whitespace outside delimiters", RichText{NewTextRun("Hello"), NewTextRun(" world ", MarkBold), NewTextRun("!")}, `Hello **world** \!`},
+		{"exclamation mark before a link", RichText{NewTextRun("Wow!"), NewLinkRun("here", "/here")}, `Wow\![here](/here)`},
+		{"whitespace only", RichText{NewTextRun("a"), NewTextRun(" ", MarkBold), NewTextRun("b")}, `a b`},
+		{"code", RichText{NewTextRun("a*b", MarkCode, MarkBold)}, "**`a*b`**"},
+		{"code with backticks", RichText{NewTextRun("`x``", MarkCode)}, "``` `x`` ```"},
+		{"link", RichText{NewLinkRun("the docs", "/docs (v1)", MarkItalic)}, `[_the docs_](/docs%20%28v1%29)`},
+		{"unsafe link", RichText{NewLinkRun("click", "javascript:alert(1)")}, `click`},
+		{"underline and mention", RichText{NewTextRun("u", MarkUnderline), NewMentionRun("@alice", "user1")}, `u@alice`},
+		{"list markers", RichText{NewTextRun("- item\n  + more\n1. two")}, "\\- item\\\n  \\+ more\\\n1\\. two"},
+		{"setext heading", RichText{NewTextRun("Title\n===")}, "Title\\\n\\==="},
+		{"markers only at the start of a line", RichText{NewTextRun("a - b = 1. c")}, `a - b = 1. c`},
+		{"line start across runs", RichText{NewTextRun("a\n", MarkBold), NewTextRun("- b")}, "**a**\\\n\\- b"},
+		{"line break in code", RichText{NewTextRun("a\nb", MarkCode)}, "`a\nb`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.richText.ToMarkdown(); got != tt.want {
+				t.Errorf("ToMarkdown() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRichText_Json(t *testing.T) {
+	richText := RichText{NewTextRun("Hello "), NewLinkRun("world", "https://example.com", MarkBold)}
+
+	richTextJson, err := richText.ToJson()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `[{"text":"Hello "},{"text":"world","marks":["link","bold"],"attributes":{"href":"https://example.com"}}]`
+
+	if richTextJson != want {
+		t.Errorf("ToJson() = %s, want %s", richTextJson, want)
+	}
+
+	got, err := NewRichTextFromJson(richTextJson)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, richText) {
+		t.Errorf("NewRichTextFromJson() = %#v, want %#v", got, richText)
+	}
+
+	if emptyJson, _ := RichText(nil).ToJson(); emptyJson != "[]" {
+		t.Errorf("ToJson() of nil rich text = %s, want []", emptyJson)
+	}
+
+	if _, err := NewRichTextFromJson(`[{"text":1}]`); !errors.Is(err, ErrInvalidRichText) {
+		t.Errorf("expected ErrInvalidRichText, got %v", err)
+	}
+}
+
+func TestBlockRichText(t *testing.T) {
+	block := NewBlockBuilder().WithID("paragraph1").WithType("paragraph").Build()
+
+	if got := BlockRichText(block); got == nil || len(got) != 0 {
+		t.Fatalf("BlockRichText() of empty content = %#v", got)
+	}
+
+	plainContents := []string{
+		"Plain [text]",
+		"[1] Smith et al.",
+		"[]",
+		`[{"text":`,
+		`["a","b"]`,
+		`[{"text":"x","marks":["blink"]}]`,
+	}
+
+	for _, content := range plainContents {
+		block.SetContent(content)
+
+		if got := BlockRichText(block); !reflect.DeepEqual(got, RichText{NewTextRun(content)}) {
+			t.Errorf("BlockRichText() of plain content %q = %#v", content, got)
+		}
+	}
+
+	// the content has no marker of its format, valid rich text JSON is decoded
+	block.SetContent(`[{"text":"x","marks":["bold"]}]`)
+
+	if got := BlockRichText(block); !reflect.DeepEqual(got, RichText{NewTextRun("x", MarkBold)}) {
+		t.Errorf("BlockRichText() of rich text JSON = %#v", got)
+	}
+
+	err := SetBlockRichText(block, RichText{NewTextRun("Hello "), NewTextRun("big", MarkBold), NewTextRun(" world", MarkBold)})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := `[{"text":"Hello "},{"text":"big world","marks":["bold"]}]`; block.Content() != want {
+		t.Errorf("Content() = %s, want %s", block.Content(), want)
+	}
+
+	if got := BlockRichText(block).ToHTML(); got != "Hello <strong>big world</strong>" {
+		t.Errorf("ToHTML() = %s", got)
+	}
+
+	if err := SetBlockRichText(block, RichText{NewLinkRun("x", "javascript:alert(1)")}); !errors.Is(err, ErrInvalidRichText) {
+		t.Errorf("expected ErrInvalidRichText, got %v", err)
+	}
+
+	if block.Content() == "" {
+		t.Error("invalid rich text must not change the content")
+	}
+
+	if err := SetBlockRichText(block, RichText{NewTextRun("")}); err != nil || block.Content() != "" {
+		t.Errorf("empty rich text must clear the content, got %q, %v", block.Content(), err)
+	}
+}
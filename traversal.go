@@ -0,0 +1,148 @@
+package ui
+
+import "errors"
+
+// SkipChildren is returned by a WalkFunc to skip the children of the block,
+// in pre-order walks. It is not returned by Walk
+var SkipChildren = errors.New("skip children")
+
+// SkipAll is returned by a WalkFunc to stop the walk.
+// It is not returned by Walk or WalkPostOrder
+var SkipAll = errors.New("skip all")
+
+// WalkFunc is called for each block visited by Walk and WalkPostOrder
+//
+// The depth of the root block is 0. Returning SkipChildren skips
+// the children of the block (pre-order only), returning SkipAll stops
+// the walk, and returning any other error stops the walk with that error
+type WalkFunc func(block BlockInterface, depth int) error
+
+// Walk visits the block and its descendants in pre-order
+// (each block before its children), calling fn for each block
+func Walk(root BlockInterface, fn WalkFunc) error {
+	err := walkPreOrder(root, 0, fn)
+
+	if errors.Is(err, SkipAll) {
+		return nil
+	}
+
+	return err
+}
+
+// WalkPostOrder visits the block and its descendants in post-order
+// (each block after its children), calling fn for each block
+func WalkPostOrder(root BlockInterface, fn WalkFunc) error {
+	err := walkPostOrder(root, 0, fn)
+
+	if errors.Is(err, SkipAll) {
+		return nil
+	}
+
+	return err
+}
+
+// FindByID returns the first block with the ID in the tree,
+// in pre-order, or nil if not found
+func FindByID(root BlockInterface, id string) BlockInterface {
+	path := FindPath(root, id)
+
+	if len(path) < 1 {
+		return nil
+	}
+
+	return path[len(path)-1]
+}
+
+// FindAllByType returns all blocks of the type in the tree, in pre-order
+func FindAllByType(root BlockInterface, blockType string) []BlockInterface {
+	return Filter(root, func(block BlockInterface) bool {
+		return block.Type() == blockType
+	})
+}
+
+// Filter returns all blocks in the tree matching the predicate, in pre-order
+func Filter(root BlockInterface, predicate func(block BlockInterface) bool) []BlockInterface {
+	blocks := []BlockInterface{}
+
+	_ = Walk(root, func(block BlockInterface, _ int) error {
+		if predicate(block) {
+			blocks = append(blocks, block)
+		}
+		return nil
+	})
+
+	return blocks
+}
+
+// FindParent returns the parent of the block with the ID in the tree,
+// or nil if the block is not found or is the root
+func FindParent(root BlockInterface, id string) BlockInterface {
+	path := FindPath(root, id)
+
+	if len(path) < 2 {
+		return nil
+	}
+
+	return path[len(path)-2]
+}
+
+// FindPath returns the blocks from the root to the block with the ID
+// (both included), or nil if the block is not found
+func FindPath(root BlockInterface, id string) []BlockInterface {
+	if root == nil {
+		return nil
+	}
+
+	if root.ID() == id {
+		return []BlockInterface{root}
+	}
+
+	for _, child := range root.Children() {
+		if path := FindPath(child, id); path != nil {
+			return append([]BlockInterface{root}, path...)
+		}
+	}
+
+	return nil
+}
+
+func walkPreOrder(block BlockInterface, depth int, fn WalkFunc) error {
+	if block == nil {
+		return nil
+	}
+
+	if err := fn(block, depth); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range block.Children() {
+		if err := walkPreOrder(child, depth+1, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkPostOrder(block BlockInterface, depth int, fn WalkFunc) error {
+	if block == nil {
+		return nil
+	}
+
+	for _, child := range block.Children() {
+		if err := walkPostOrder(child, depth+1, fn); err != nil {
+			return err
+		}
+	}
+
+	err := fn(block, depth)
+
+	if errors.Is(err, SkipChildren) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newTestTree returns the tree:
+//
+//	document1
+//	├── page1
+//	│   ├── paragraph1
+//	│   └── image1
+//	└── page2
+//	    ├── image2
+//	    └── paragraph2
+func newTestTree() BlockInterface {
+	newTestBlock := func(id, blockType string, children ...BlockInterface) BlockInterface {
+		return NewBlockBuilder().WithID(id).WithType(blockType).WithChildren(children).Build()
+	}
+
+	return newTestBlock("document1", "document",
+		newTestBlock("page1", "page",
+			newTestBlock("paragraph1", "paragraph"),
+			newTestBlock("image1", "image"),
+		),
+		newTestBlock("page2", "page",
+			newTestBlock("image2", "image"),
+			newTestBlock("paragraph2", "paragraph"),
+		),
+	)
+}
+
+func blockIDs(blocks []BlockInterface) []string {
+	ids := []string{}
+	for _, block := range blocks {
+		ids = append(ids, block.ID())
+	}
+	return ids
+}
+
+func TestWalk(t *testing.T) {
+	visited := []string{}
+	depths := []int{}
+
+	err := Walk(newTestTree(), func(block BlockInterface, depth int) error {
+		visited = append(visited, block.ID())
+		depths = append(depths, depth)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantVisited := []string{"document1", "page1", "paragraph1", "image1", "page2", "image2", "paragraph2"}
+	wantDepths := []int{0, 1, 2, 2, 1, 2, 2}
+
+	if !reflect.DeepEqual(visited, wantVisited) {
+		t.Errorf("visited = %v, want %v", visited, wantVisited)
+	}
+
+	if !reflect.DeepEqual(depths, wantDepths) {
+		t.Errorf("depths = %v, want %v", depths, wantDepths)
+	}
+}
+
+func TestWalk_SkipChildren(t *testing.T) {
+	visited := []string{}
+
+	err := Walk(newTestTree(), func(block BlockInterface, _ int) error {
+		visited = append(visited, block.ID())
+		if block.ID() == "page1" {
+			return SkipChildren
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"document1", "page1", "page2", "image2", "paragraph2"}
+
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited = %v, want %v", visited, want)
+	}
+}
+
+func TestWalk_SkipAll(t *testing.T) {
+	visited := []string{}
+
+	err := Walk(newTestTree(), func(block BlockInterface, _ int) error {
+		visited = append(visited, block.ID())
+		if block.ID() == "paragraph1" {
+			return SkipAll
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"document1", "page1", "paragraph1"}
+
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited = %v, want %v", visited, want)
+	}
+}
+
+func TestWalk_Error(t *testing.T) {
+	errStop := errors.New("stop")
+
+	err := Walk(newTestTree(), func(block BlockInterface, _ int) error {
+		if block.ID() == "page2" {
+			return errStop
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected errStop, got %v", err)
+	}
+}
+
+func TestWalkPostOrder(t *testing.T) {
+	visited := []string{}
+
+	err := WalkPostOrder(newTestTree(), func(block BlockInterface, _ int) error {
+		visited = append(visited, block.ID())
+		if block.ID() == "image2" {
+			return SkipAll
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"paragraph1", "image1", "page1", "image2"}
+
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited = %v, want %v", visited, want)
+	}
+}
+
+func TestFindByID(t *testing.T) {
+	tree := newTestTree()
+
+	if block := FindByID(tree, "image2"); block == nil || block.ID() != "image2" {
+		t.Errorf("FindByID(image2) = %v", block)
+	}
+
+	if block := FindByID(tree, "document1"); block != tree {
+		t.Errorf("FindByID(document1) = %v, want root", block)
+	}
+
+	if block := FindByID(tree, "missing"); block != nil {
+		t.Errorf("FindByID(missing) = %v, want nil", block)
+	}
+
+	if block := FindByID(nil, "image2"); block != nil {
+		t.Errorf("FindByID(nil) = %v, want nil", block)
+	}
+}
+
+func TestFindAllByType(t *testing.T) {
+	got := blockIDs(FindAllByType(newTestTree(), "image"))
+	want := []string{"image1", "image2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllByType(image) = %v, want %v", got, want)
+	}
+
+	if got := FindAllByType(newTestTree(), "video"); len(got) != 0 {
+		t.Errorf("FindAllByType(video) = %v, want empty", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	got := blockIDs(Filter(newTestTree(), func(block BlockInterface) bool {
+		return len(block.Children()) > 0
+	}))
+	want := []string{"document1", "page1", "page2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %v, want %v", got, want)
+	}
+}
+
+func TestFindParent(t *testing.T) {
+	tree := newTestTree()
+
+	if parent := FindParent(tree, "paragraph2"); parent == nil || parent.ID() != "page2" {
+		t.Errorf("FindParent(paragraph2) = %v, want page2", parent)
+	}
+
+	if parent := FindParent(tree, "document1"); parent != nil {
+		t.Errorf("FindParent(document1) = %v, want nil", parent)
+	}
+
+	if parent := FindParent(tree, "missing"); parent != nil {
+		t.Errorf("FindParent(missing) = %v, want nil", parent)
+	}
+}
+
+func TestFindPath(t *testing.T) {
+	got := blockIDs(FindPath(newTestTree(), "image1"))
+	want := []string{"document1", "page1", "image1"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindPath(image1) = %v, want %v", got, want)
+	}
+
+	if path := FindPath(newTestTree(), "missing"); path != nil {
+		t.Errorf("FindPath(missing) = %v, want nil", path)
+	}
+}
+
+type testWrapperBlock struct {
+	BlockInterface
+}
+
+func TestTraversal_AnyBlockInterface(t *testing.T) {
+	child := &testWrapperBlock{NewBlockBuilder().WithID("child").WithType("image").Build()}
+	root := &testWrapperBlock{NewBlockBuilder().WithID("root").WithType("page").WithChildren([]BlockInterface{child}).Build()}
+
+	if block := FindByID(root, "child"); block != child {
+		t.Errorf("FindByID(child) = %v, want the wrapper", block)
+	}
+
+	if got := FindAllByType(root, "image"); len(got) != 1 || got[0] != child {
+		t.Errorf("FindAllByType(image) = %v, want the wrapper", got)
+	}
+}
@@ -0,0 +1,267 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrParameterNotFound is returned when a typed parameter is not set,
+// or is set to an empty string
+var ErrParameterNotFound = errors.New("parameter not found")
+
+// Typed parameters are stored as strings, encoded as follows:
+// - int, bool and float - with strconv (i.e. "42", "true", "0.5")
+// - time - in the time.RFC3339Nano layout, dates in the ParameterDateLayout are also read
+// - duration - with time.Duration.String (i.e. "1m30s")
+// - strings and JSON - as JSON (i.e. `["a","b"]`)
+//
+// Each type has three functions:
+// - ParseParameterX - returns the value, ErrParameterNotFound if the
+// parameter is missing, or ErrParameterInvalid if it can not be parsed
+// - ParameterX - returns the value, or the default if the parameter
+// is missing or invalid
+// - SetParameterX - encodes and sets the value
+//
+// The values are read with Parameter, so the defaults of a registered
+// schema (see RegisterSchema) are used for parameters which are not set
+
+// == INT =====================================================================
+
+// ParseParameterInt returns the parameter as an int
+func ParseParameterInt(parameters ParametersInterface, key string) (int, error) {
+	return parseParameter(parameters, key, strconv.Atoi)
+}
+
+// ParameterInt returns the parameter as an int, or the default
+func ParameterInt(parameters ParametersInterface, key string, defaultValue int) int {
+	value, err := ParseParameterInt(parameters, key)
+
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// SetParameterInt sets the parameter to the int
+func SetParameterInt(parameters ParametersInterface, key string, value int) {
+	parameters.SetParameter(key, strconv.Itoa(value))
+}
+
+// == BOOL ====================================================================
+
+// ParseParameterBool returns the parameter as a bool, accepting
+// the values of strconv.ParseBool (i.e. "true", "false", "1", "0")
+func ParseParameterBool(parameters ParametersInterface, key string) (bool, error) {
+	return parseParameter(parameters, key, strconv.ParseBool)
+}
+
+// ParameterBool returns the parameter as a bool, or the default
+func ParameterBool(parameters ParametersInterface, key string, defaultValue bool) bool {
+	value, err := ParseParameterBool(parameters, key)
+
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// SetParameterBool sets the parameter to "true" or "false"
+func SetParameterBool(parameters ParametersInterface, key string, value bool) {
+	parameters.SetParameter(key, strconv.FormatBool(value))
+}
+
+// == FLOAT ===================================================================
+
+// ParseParameterFloat returns the parameter as a float64
+func ParseParameterFloat(parameters ParametersInterface, key string) (float64, error) {
+	return parseParameter(parameters, key, func(value string) (float64, error) {
+		return strconv.ParseFloat(value, 64)
+	})
+}
+
+// ParameterFloat returns the parameter as a float64, or the default
+func ParameterFloat(parameters ParametersInterface, key string, defaultValue float64) float64 {
+	value, err := ParseParameterFloat(parameters, key)
+
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// SetParameterFloat sets the parameter to the shortest representation of the float64
+func SetParameterFloat(parameters ParametersInterface, key string, value float64) {
+	parameters.SetParameter(key, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// == TIME ====================================================================
+
+// ParseParameterTime returns the parameter as a time, in the time.RFC3339Nano
+// layout, or in the ParameterDateLayout (as midnight UTC)
+func ParseParameterTime(parameters ParametersInterface, key string) (time.Time, error) {
+	return parseParameter(parameters, key, func(value string) (time.Time, error) {
+		if date, err := time.Parse(ParameterDateLayout, value); err == nil {
+			return date, nil
+		}
+
+		return time.Parse(time.RFC3339Nano, value)
+	})
+}
+
+// ParameterTime returns the parameter as a time, or the default
+func ParameterTime(parameters ParametersInterface, key string, defaultValue time.Time) time.Time {
+	value, err := ParseParameterTime(parameters, key)
+
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// SetParameterTime sets the parameter to the time, in the time.RFC3339Nano layout
+func SetParameterTime(parameters ParametersInterface, key string, value time.Time) {
+	parameters.SetParameter(key, value.Format(time.RFC3339Nano))
+}
+
+// == DURATION ================================================================
+
+// ParseParameterDuration returns the parameter as a duration,
+// in the format of time.ParseDuration (i.e. "1m30s")
+func ParseParameterDuration(parameters ParametersInterface, key string) (time.Duration, error) {
+	return parseParameter(parameters, key, time.ParseDuration)
+}
+
+// ParameterDuration returns the parameter as a duration, or the default
+func ParameterDuration(parameters ParametersInterface, key string, defaultValue time.Duration) time.Duration {
+	value, err := ParseParameterDuration(parameters, key)
+
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// SetParameterDuration sets the parameter to the duration (i.e. "1m30s")
+func SetParameterDuration(parameters ParametersInterface, key string, value time.Duration) {
+	parameters.SetParameter(key, value.String())
+}
+
+// == STRINGS =================================================================
+
+// ParseParameterStrings returns the parameter as a list of strings,
+// stored as a JSON array
+func ParseParameterStrings(parameters ParametersInterface, key string) ([]string, error) {
+	return parseParameter(parameters, key, func(value string) ([]string, error) {
+		values := []string{}
+
+		if err := json.Unmarshal([]byte(value), &values); err != nil {
+			return nil, err
+		}
+
+		if values == nil {
+			values = []string{}
+		}
+
+		return values, nil
+	})
+}
+
+// ParameterStrings returns the parameter as a list of strings, or the default
+func ParameterStrings(parameters ParametersInterface, key string, defaultValue []string) []string {
+	value, err := ParseParameterStrings(parameters, key)
+
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// SetParameterStrings sets the parameter to the list of strings, as a JSON array
+func SetParameterStrings(parameters ParametersInterface, key string, values []string) {
+	if values == nil {
+		values = []string{}
+	}
+
+	valuesJson, _ := json.Marshal(values) // a []string can always be marshaled
+
+	parameters.SetParameter(key, string(valuesJson))
+}
+
+// == JSON ====================================================================
+
+// ParseParameterJSON decodes the parameter, stored as JSON, into the value
+// pointed to by into
+func ParseParameterJSON(parameters ParametersInterface, key string, into any) error {
+	_, err := parseParameter(parameters, key, func(value string) (any, error) {
+		return nil, json.Unmarshal([]byte(value), into)
+	})
+
+	return err
+}
+
+// ParameterJSON decodes the parameter, stored as JSON, into the value
+// pointed to by into, and reports if it was decoded
+//
+// The value of into is the default, it is left unchanged if the parameter
+// is missing or invalid
+func ParameterJSON(parameters ParametersInterface, key string, into any) bool {
+	target := reflect.ValueOf(into)
+
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return false
+	}
+
+	decoded := reflect.New(target.Elem().Type())
+
+	if err := ParseParameterJSON(parameters, key, decoded.Interface()); err != nil {
+		return false
+	}
+
+	target.Elem().Set(decoded.Elem())
+	return true
+}
+
+// SetParameterJSON sets the parameter to the value, encoded as JSON
+func SetParameterJSON(parameters ParametersInterface, key string, value any) error {
+	valueJson, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Errorf("parameter %q: %w", key, err)
+	}
+
+	parameters.SetParameter(key, string(valueJson))
+	return nil
+}
+
+// == HELPERS =================================================================
+
+// parseParameter parses the value of the parameter, returning
+// ErrParameterNotFound if it is empty, or ErrParameterInvalid
+// if it can not be parsed
+func parseParameter[T any](parameters ParametersInterface, key string, parse func(string) (T, error)) (T, error) {
+	var zero T
+
+	value := parameters.Parameter(key)
+
+	if value == "" {
+		return zero, fmt.Errorf("parameter %q: %w", key, ErrParameterNotFound)
+	}
+
+	parsed, err := parse(value)
+
+	if err != nil {
+		return zero, fmt.Errorf("parameter %q: %w: %v", key, ErrParameterInvalid, err)
+	}
+
+	return parsed, nil
+}
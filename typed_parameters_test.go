@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTypedParameters_Int(t *testing.T) {
+	block := NewBlock()
+	SetParameterInt(block, "width", -42)
+
+	if block.Parameter("width") != "-42" {
+		t.Errorf("SetParameterInt() stored %q", block.Parameter("width"))
+	}
+
+	if got, err := ParseParameterInt(block, "width"); err != nil || got != -42 {
+		t.Errorf("ParseParameterInt() = %d, %v", got, err)
+	}
+
+	if _, err := ParseParameterInt(block, "height"); !errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("expected ErrParameterNotFound, got %v", err)
+	}
+
+	block.SetParameter("height", "tall")
+
+	if _, err := ParseParameterInt(block, "height"); !errors.Is(err, ErrParameterInvalid) {
+		t.Errorf("expected ErrParameterInvalid, got %v", err)
+	}
+
+	if got := ParameterInt(block, "height", 10); got != 10 {
+		t.Errorf("ParameterInt() of invalid value = %d, want the default", got)
+	}
+
+	if got := ParameterInt(block, "missing", 10); got != 10 {
+		t.Errorf("ParameterInt() of missing value = %d, want the default", got)
+	}
+
+	if got := ParameterInt(block, "width", 10); got != -42 {
+		t.Errorf("ParameterInt() = %d, want -42", got)
+	}
+}
+
+func TestTypedParameters_Bool(t *testing.T) {
+	block := NewBlock()
+	SetParameterBool(block, "visible", true)
+
+	if block.Parameter("visible") != "true" {
+		t.Errorf("SetParameterBool() stored %q", block.Parameter("visible"))
+	}
+
+	if got, err := ParseParameterBool(block, "visible"); err != nil || !got {
+		t.Errorf("ParseParameterBool() = %v, %v", got, err)
+	}
+
+	block.SetParameter("visible", "0")
+
+	if got := ParameterBool(block, "visible", true); got {
+		t.Error("ParameterBool() of 0 must be false")
+	}
+
+	block.SetParameter("visible", "")
+
+	if _, err := ParseParameterBool(block, "visible"); !errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("empty value must be ErrParameterNotFound, got %v", err)
+	}
+
+	if got := ParameterBool(block, "visible", true); !got {
+		t.Error("ParameterBool() of empty value must be the default")
+	}
+}
+
+func TestTypedParameters_Float(t *testing.T) {
+	block := NewBlock()
+	SetParameterFloat(block, "ratio", 0.1)
+
+	if block.Parameter("ratio") != "0.1" {
+		t.Errorf("SetParameterFloat() stored %q", block.Parameter("ratio"))
+	}
+
+	if got, err := ParseParameterFloat(block, "ratio"); err != nil || got != 0.1 {
+		t.Errorf("ParseParameterFloat() = %v, %v", got, err)
+	}
+
+	SetParameterFloat(block, "ratio", 1e21)
+
+	if got := ParameterFloat(block, "ratio", 0); got != 1e21 {
+		t.Errorf("ParameterFloat() = %v, want 1e21", got)
+	}
+
+	block.SetParameter("ratio", "1/2")
+
+	if got := ParameterFloat(block, "ratio", 0.5); got != 0.5 {
+		t.Errorf("ParameterFloat() of invalid value = %v, want the default", got)
+	}
+}
+
+func TestTypedParameters_Time(t *testing.T) {
+	block := NewBlock()
+	value := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("CEST", 2*60*60))
+
+	SetParameterTime(block, "published", value)
+
+	if block.Parameter("published") != "2024-05-06T07:08:09.123456789+02:00" {
+		t.Errorf("SetParameterTime() stored %q", block.Parameter("published"))
+	}
+
+	if got, err := ParseParameterTime(block, "published"); err != nil || !got.Equal(value) {
+		t.Errorf("ParseParameterTime() = %v, %v", got, err)
+	}
+
+	block.SetParameter("published", "2024-05-06")
+
+	if got := ParameterTime(block, "published", time.Time{}); !got.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParameterTime() of a date = %v", got)
+	}
+
+	block.SetParameter("published", "yesterday")
+
+	if _, err := ParseParameterTime(block, "published"); !errors.Is(err, ErrParameterInvalid) {
+		t.Errorf("expected ErrParameterInvalid, got %v", err)
+	}
+
+	if got := ParameterTime(block, "published", value); !got.Equal(value) {
+		t.Errorf("ParameterTime() of invalid value = %v, want the default", got)
+	}
+}
+
+func TestTypedParameters_Duration(t *testing.T) {
+	block := NewBlock()
+	SetParameterDuration(block, "interval", 90*time.Second)
+
+	if block.Parameter("interval") != "1m30s" {
+		t.Errorf("SetParameterDuration() stored %q", block.Parameter("interval"))
+	}
+
+	if got, err := ParseParameterDuration(block, "interval"); err != nil || got != 90*time.Second {
+		t.Errorf("ParseParameterDuration() = %v, %v", got, err)
+	}
+
+	block.SetParameter("interval", "90")
+
+	if got := ParameterDuration(block, "interval", time.Second); got != time.Second {
+		t.Errorf("ParameterDuration() of invalid value = %v, want the default", got)
+	}
+}
+
+func TestTypedParameters_Strings(t *testing.T) {
+	block := NewBlock()
+	SetParameterStrings(block, "classes", []string{"a", `b "c"`, "d,e"})
+
+	if block.Parameter("classes") != `["a","b \"c\"","d,e"]` {
+		t.Errorf("SetParameterStrings() stored %q", block.Parameter("classes"))
+	}
+
+	if got, err := ParseParameterStrings(block, "classes"); err != nil || !reflect.DeepEqual(got, []string{"a", `b "c"`, "d,e"}) {
+		t.Errorf("ParseParameterStrings() = %v, %v", got, err)
+	}
+
+	SetParameterStrings(block, "classes", nil)
+
+	if got, err := ParseParameterStrings(block, "classes"); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("ParseParameterStrings() of empty list = %#v, %v", got, err)
+	}
+
+	block.SetParameter("classes", "null")
+
+	if got := ParameterStrings(block, "classes", []string{"x"}); got == nil || len(got) != 0 {
+		t.Errorf("ParameterStrings() of null = %#v, want empty list", got)
+	}
+
+	block.SetParameter("classes", "a b")
+
+	if got := ParameterStrings(block, "classes", []string{"x"}); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("ParameterStrings() of invalid value = %v, want the default", got)
+	}
+}
+
+func TestTypedParameters_JSON(t *testing.T) {
+	type size struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}
+
+	block := NewBlock()
+
+	if err := SetParameterJSON(block, "size", size{Width: 3, Height: 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if block.Parameter("size") != `{"width":3,"height":4}` {
+		t.Errorf("SetParameterJSON() stored %q", block.Parameter("size"))
+	}
+
+	got := size{}
+
+	if err := ParseParameterJSON(block, "size", &got); err != nil || got != (size{3, 4}) {
+		t.Errorf("ParseParameterJSON() = %v, %v", got, err)
+	}
+
+	if err := ParseParameterJSON(block, "missing", &got); !errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("expected ErrParameterNotFound, got %v", err)
+	}
+
+	if err := SetParameterJSON(block, "invalid", func() {}); err == nil {
+		t.Error("expected an error for a value which can not be marshaled")
+	}
+
+	if block.HasParameter("invalid") {
+		t.Error("the parameter must not be set when the value can not be marshaled")
+	}
+
+	block.SetParameter("size", `{"width":5,"height":"tall"}`)
+
+	if err := ParseParameterJSON(block, "size", &size{}); !errors.Is(err, ErrParameterInvalid) {
+		t.Errorf("expected ErrParameterInvalid, got %v", err)
+	}
+
+	defaultSize := size{Width: 1, Height: 1}
+
+	if ParameterJSON(block, "size", &defaultSize) || defaultSize != (size{1, 1}) {
+		t.Errorf("ParameterJSON() of invalid value must keep the default, got %v", defaultSize)
+	}
+
+	block.SetParameter("size", `{"width":5}`)
+
+	if !ParameterJSON(block, "size", &defaultSize) || defaultSize != (size{Width: 5}) {
+		t.Errorf("ParameterJSON() = %v, want {5 0}", defaultSize)
+	}
+
+	if ParameterJSON(block, "size", nil) {
+		t.Error("ParameterJSON() into nil must report false")
+	}
+}
+
+func TestTypedParameters_SchemaDefault(t *testing.T) {
+	err := RegisterSchema(BlockSchema{
+		Type: "typed_parameters_test",
+		Parameters: []ParameterSchema{
+			{Name: "columns", Kind: ParameterKindInt, Default: "3"},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer UnregisterSchema("typed_parameters_test")
+
+	block := NewBlockBuilder().WithType("typed_parameters_test").Build()
+
+	if got := ParameterInt(block, "columns", 1); got != 3 {
+		t.Errorf("ParameterInt() = %d, want the schema default 3", got)
+	}
+}
@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// WireFormatVersion is the version of the document JSON written by
+// MarshalDocumentEnvelope. Documents of older versions are migrated on load
+// (see RegisterMigration)
+//
+// Versions:
+// - 0: unversioned payloads, a block or a JSON array of blocks
+// - 1: the DocumentJsonObject envelope
+const WireFormatVersion = 1
+
+// ErrUnsupportedVersion is returned when loading a document of a version
+// newer than WireFormatVersion, or which can not be migrated
+var ErrUnsupportedVersion = errors.New("unsupported wire format version")
+
+// DocumentJsonObject is the JSON envelope of a stored document
+//
+// Example:
+//
+//	{"version":1,"blocks":[{"id":"page1","type":"page","content":"","parameters":{},"children":[]}]}
+type DocumentJsonObject struct {
+	// Version is the wire format version of the document
+	Version int `json:"version"`
+
+	// Blocks are the root blocks of the document
+	Blocks []BlockJsonObject `json:"blocks"`
+}
+
+// Migration upgrades a document from one wire format version to the next
+//
+// The document is the decoded JSON of the document envelope, with numbers
+// as json.Number. The migration changes it in place, the version field
+// is updated after the migration
+type Migration func(document map[string]any) error
+
+// migrationRegistry holds the migrations keyed by the version they upgrade from
+var migrationRegistry = struct {
+	mu         sync.RWMutex
+	migrations map[int]Migration
+}{
+	migrations: map[int]Migration{
+		0: migrateLegacyDocument,
+	},
+}
+
+// RegisterMigration registers the migration of documents from the version
+// to the next version, replacing the migration registered for the version
+//
+// Example:
+//
+//	// legacy documents used the "text" type for paragraphs
+//	ui.RegisterMigration(0, func(document map[string]any) error {
+//		...
+//	})
+func RegisterMigration(fromVersion int, migration Migration) {
+	migrationRegistry.mu.Lock()
+	defer migrationRegistry.mu.Unlock()
+	migrationRegistry.migrations[fromVersion] = migration
+}
+
+func migrationFor(fromVersion int) Migration {
+	migrationRegistry.mu.RLock()
+	defer migrationRegistry.mu.RUnlock()
+	return migrationRegistry.migrations[fromVersion]
+}
+
+// migrateLegacyDocument upgrades unversioned payloads, which are
+// wrapped in an envelope on load, the blocks are unchanged
+func migrateLegacyDocument(map[string]any) error {
+	return nil
+}
+
+// MarshalDocumentEnvelope returns the JSON of the document envelope
+// of the blocks, with the current WireFormatVersion
+func MarshalDocumentEnvelope(blocks []BlockInterface) (string, error) {
+	document := DocumentJsonObject{
+		Version: WireFormatVersion,
+		Blocks:  []BlockJsonObject{},
+	}
+
+	for _, block := range blocks {
+		if block == nil {
+			continue
+		}
+
+		document.Blocks = append(document.Blocks, block.ToJsonObject())
+	}
+
+	documentJson, err := json.Marshal(document)
+
+	return string(documentJson), err
+}
+
+// UnmarshalDocumentEnvelope returns the blocks of a document
+//
+// The document is either a document envelope, or a legacy unversioned
+// payload (a block, or a JSON array of blocks), which is version 0.
+// Documents of older versions are migrated to WireFormatVersion first
+//
+// No decode limits are enforced, to decode JSON from untrusted
+// sources use UnmarshalDocumentEnvelopeWithOptions
+func UnmarshalDocumentEnvelope(documentJson string) ([]BlockInterface, error) {
+	return unmarshalDocumentEnvelope([]byte(documentJson), &decodeState{})
+}
+
+// UnmarshalDocumentEnvelopeWithOptions returns the blocks of a document,
+// like UnmarshalDocumentEnvelope, enforcing the limits of the options
+func UnmarshalDocumentEnvelopeWithOptions(documentJson string, options DecodeOptions) ([]BlockInterface, error) {
+	state := &decodeState{options: options}
+
+	if err := state.checkInput([]byte(documentJson), 2); err != nil {
+		return nil, err
+	}
+
+	return unmarshalDocumentEnvelope([]byte(documentJson), state)
+}
+
+func unmarshalDocumentEnvelope(data []byte, state *decodeState) ([]BlockInterface, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var payload any
+
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, jsonErrorToDecodeError(err, "$")
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: data after the document")
+	}
+
+	document, version, err := documentEnvelope(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if version < 0 || version > WireFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	for ; version < WireFormatVersion; version++ {
+		migration := migrationFor(version)
+
+		if migration == nil {
+			return nil, fmt.Errorf("%w: no migration from version %d", ErrUnsupportedVersion, version)
+		}
+
+		if err := migration(document); err != nil {
+			return nil, fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+
+		document["version"] = json.Number(fmt.Sprint(version + 1))
+	}
+
+	blocks, exists := document["blocks"]
+
+	if !exists {
+		return nil, &DecodeError{Path: "$.blocks", Err: ErrMissingField}
+	}
+
+	blocksJson, err := json.Marshal(blocks)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalJsonToBlocks(blocksJson, "$.blocks", state)
+}
+
+// documentEnvelope returns the envelope of the payload, and its version.
+// Legacy payloads are wrapped in an envelope of version 0
+func documentEnvelope(payload any) (map[string]any, int, error) {
+	switch payload := payload.(type) {
+	case []any:
+		return map[string]any{"version": json.Number("0"), "blocks": payload}, 0, nil
+	case map[string]any:
+		versionAny, exists := payload["version"]
+
+		if !exists {
+			return map[string]any{"version": json.Number("0"), "blocks": []any{payload}}, 0, nil
+		}
+
+		versionNumber, ok := versionAny.(json.Number)
+
+		if !ok {
+			return nil, 0, invalidFieldType("$.version", "integer", versionAny)
+		}
+
+		version, err := versionNumber.Int64()
+
+		if err != nil {
+			return nil, 0, &DecodeError{Path: "$.version", Err: fmt.Errorf("%w: expected integer, got %s", ErrInvalidFieldType, versionNumber)}
+		}
+
+		return payload, int(version), nil
+	}
+
+	return nil, 0, invalidFieldType("$", "object or array", payload)
+}
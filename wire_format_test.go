@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMarshalDocumentEnvelope(t *testing.T) {
+	page := NewBlockBuilder().WithID("page1").WithType("page").WithParameters(map[string]string{"title": "Home"}).Build()
+
+	got, err := MarshalDocumentEnvelope([]BlockInterface{page, nil})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"version":1,"blocks":[{"id":"page1","type":"page","content":"","parameters":{"title":"Home"},"children":[]}]}`
+
+	if got != want {
+		t.Errorf("MarshalDocumentEnvelope() = %s, want %s", got, want)
+	}
+
+	blocks, err := UnmarshalDocumentEnvelope(got)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(blocks) != 1 || mustToJson(t, blocks[0]) != mustToJson(t, page) {
+		t.Errorf("UnmarshalDocumentEnvelope() = %v", blocks)
+	}
+}
+
+func TestUnmarshalDocumentEnvelope_Legacy(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		ids  []string
+	}{
+		{"block array", `[{"id":"page1","type":"page"},{"id":"page2","type":"page"}]`, []string{"page1", "page2"}},
+		{"single block", `{"id":"page1","type":"page","children":[{"id":"text1","type":"text"}]}`, []string{"page1"}},
+		{"version 0", `{"version":0,"blocks":[{"id":"page1","type":"page"}]}`, []string{"page1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := UnmarshalDocumentEnvelope(tt.json)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := blockIDs(blocks); len(got) != len(tt.ids) || got[0] != tt.ids[0] {
+				t.Errorf("blocks = %v, want %v", got, tt.ids)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDocumentEnvelope_Migration(t *testing.T) {
+	RegisterMigration(0, func(document map[string]any) error {
+		for _, block := range document["blocks"].([]any) {
+			if block := block.(map[string]any); block["type"] == "text" {
+				block["type"] = "paragraph"
+			}
+		}
+		return nil
+	})
+
+	t.Cleanup(func() { RegisterMigration(0, migrateLegacyDocument) })
+
+	blocks, err := UnmarshalDocumentEnvelope(`[{"id":"text1","type":"text"},{"id":"image1","type":"image","parameters":{"width":"100"}}]`)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if blocks[0].Type() != "paragraph" || blocks[1].Type() != "image" || blocks[1].Parameter("width") != "100" {
+		t.Errorf("unexpected migrated blocks %v %v", blocks[0].ToMap(), blocks[1].ToMap())
+	}
+
+	blocks, err = UnmarshalDocumentEnvelope(`{"version":1,"blocks":[{"id":"text1","type":"text"}]}`)
+
+	if err != nil || blocks[0].Type() != "text" {
+		t.Errorf("documents of the current version must not be migrated: %v", err)
+	}
+
+	RegisterMigration(0, func(map[string]any) error { return errors.New("failed") })
+
+	if _, err := UnmarshalDocumentEnvelope(`[]`); err == nil {
+		t.Error("expected the migration error")
+	}
+}
+
+func TestUnmarshalDocumentEnvelope_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr error
+	}{
+		{"newer version", `{"version":2,"blocks":[]}`, ErrUnsupportedVersion},
+		{"negative version", `{"version":-1,"blocks":[]}`, ErrUnsupportedVersion},
+		{"invalid version", `{"version":"1","blocks":[]}`, ErrInvalidFieldType},
+		{"missing blocks", `{"version":1}`, ErrMissingField},
+		{"invalid block", `{"version":1,"blocks":[{"id":"page1"}]}`, ErrMissingField},
+		{"not a document", `"page1"`, ErrInvalidFieldType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalDocumentEnvelope(tt.json)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	var decodeErr *DecodeError
+
+	if _, err := UnmarshalDocumentEnvelope(`{"version":1,"blocks":[{"id":"page1"}]}`); !errors.As(err, &decodeErr) || decodeErr.Path != "$.blocks[0].type" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := UnmarshalDocumentEnvelope(`{"version":"1","blocks":[]}`); err == nil || err.Error() != "$.version: invalid field type: expected integer, got string" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := UnmarshalDocumentEnvelope(`{"version":1.5,"blocks":[]}`); err == nil || err.Error() != "$.version: invalid field type: expected integer, got 1.5" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := UnmarshalDocumentEnvelope(`[] []`); err == nil {
+		t.Error("expected an error for data after the document")
+	}
+
+	options := DecodeOptions{MaxBlocks: 1}
+
+	if _, err := UnmarshalDocumentEnvelopeWithOptions(`{"version":1,"blocks":[{"id":"a","type":"a"},{"id":"b","type":"b"}]}`, options); !errors.Is(err, ErrDecodeLimitExceeded) {
+		t.Errorf("expected ErrDecodeLimitExceeded, got %v", err)
+	}
+}